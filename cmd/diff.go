@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+)
+
+// Dry-run strategies for --dry-run
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
+// templateDiff returns a unified diff of a workload's pod template before and after an update
+func templateDiff(workload k8s.Workload, before, after *corev1.PodTemplateSpec) (string, error) {
+	from, err := yaml.Marshal(before)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pod template of %s: %v", workload, err)
+	}
+	to, err := yaml.Marshal(after)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pod template of %s: %v", workload, err)
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: workload.String() + " (live)",
+		ToFile:   workload.String() + " (updated)",
+		Context:  3,
+	})
+}
+
+// previewDiff previews the update of a workload and returns the diff of its pod template
+func (o *SetImageOptions) previewDiff(workload k8s.Workload, serverDryRun bool) (string, error) {
+	before, after, err := o.k8sClient.PreviewContainerImages(workload, o.images, serverDryRun)
+	if err != nil {
+		return "", err
+	}
+	return templateDiff(workload, before, after)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import "fmt"
+
+// Exit codes for watch mode outcomes, so that pipelines can tell them apart.
+// When several workloads are watched the highest code wins.
+const (
+	ExitRolledBack       = 3 // The rollout failed and was rolled back
+	ExitRollbackDeclined = 4 // The rollout failed and was left as is (declined or --rollback=never)
+	ExitRollbackFailed   = 5 // The rollout failed and rolling back failed too
+)
+
+// ExitError is an error that makes the process exit with a specific code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitErrorf creates an ExitError with a formatted message
+func exitErrorf(code int, format string, args ...interface{}) *ExitError {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// exitOutcome returns the update outcome reported for a rollback exit code
+func exitOutcome(code int) string {
+	switch code {
+	case ExitRolledBack:
+		return outcomeRolledBack
+	case ExitRollbackDeclined:
+		return outcomeRollbackDeclined
+	}
+	return outcomeRollbackFailed
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/yaml"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+)
+
+type HistoryOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	k8sClient   *k8s.Client
+
+	workload k8s.Workload
+
+	// Flags
+	output string
+}
+
+func NewHistoryOptions(configFlags *genericclioptions.ConfigFlags) *HistoryOptions {
+	return &HistoryOptions{
+		configFlags: configFlags,
+		output:      "table",
+	}
+}
+
+func (o *HistoryOptions) Complete(args []string) error {
+	switch o.output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid --output %q: must be table, json or yaml", o.output)
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("history takes a single deployment")
+	}
+
+	var err error
+	o.k8sClient, err = k8s.NewClient(o.configFlags)
+	if err != nil {
+		return err
+	}
+
+	o.workload, err = resolveDeployment(o.k8sClient, args[0])
+	return err
+}
+
+func (o *HistoryOptions) Run() error {
+	changes, err := o.k8sClient.GetImageHistory(o.workload)
+	if err != nil {
+		return err
+	}
+
+	switch o.output {
+	case "json":
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		printImageChanges(changes)
+	}
+	return nil
+}
+
+func printImageChanges(changes []k8s.ImageChange) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tAGE\tCONTAINER\tPREVIOUS\tIMAGE\tUSER\tVERSION\tREASON")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Revision, duration.HumanDuration(time.Since(c.Time)), c.Container,
+			orNone(c.PreviousImage), c.Image, orNone(c.User), orNone(c.Version), orNone(c.Reason))
+	}
+	tw.Flush()
+}
+
+// orNone shows missing values as <none>, like kubectl
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func NewHistoryCommand(configFlags *genericclioptions.ConfigFlags) *cobra.Command {
+	opts := NewHistoryOptions(configFlags)
+
+	cmd := &cobra.Command{
+		Use:   "history (DEPLOYMENT | deployment/NAME)",
+		Short: "Show the image changes of a deployment",
+		Long: `Show a timeline of image changes per container of a deployment.
+
+Changes are found by comparing the deployment's ReplicaSet revisions. Changes made by
+setimg also show the previous image, the user, the setimg version and the --reason
+given, from the annotations setimg records on every update.`,
+		Example: `  # Timeline as a table
+  kubectl setimg history my-app
+
+  # As JSON or YAML
+  kubectl setimg history my-app -o json
+  kubectl setimg history deploy/my-app -o yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+			return opts.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "Output format: table, json or yaml")
+
+	return cmd
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+)
+
+// outputAPIVersion is the apiVersion of the lists printed with -o json, yaml and templates
+const outputAPIVersion = "setimg.tkuchiki.github.io/v1alpha1"
+
+// Outcomes of an image update, as printed in the OUTCOME column
+const (
+	outcomeUpdated          = "updated"
+	outcomeReady            = "ready"
+	outcomePaused           = "paused"
+	outcomeRolledBack       = "rolled back"
+	outcomeRollbackDeclined = "failed, not rolled back"
+	outcomeRollbackFailed   = "rollback failed"
+)
+
+// containerRow is a container of a workload, as printed by --list
+type containerRow struct {
+	Workload  string         `json:"workload"`
+	Container string         `json:"container"`
+	Type      string         `json:"type"`
+	Image     string         `json:"image"`
+	Pod       string         `json:"pod,omitempty"`
+	Ready     string         `json:"ready"`
+	Drift     string         `json:"drift,omitempty"`
+	Pods      []k8s.PodImage `json:"pods,omitempty"`
+}
+
+// updateRow is the result of an image update of one container
+type updateRow struct {
+	Workload      string         `json:"workload"`
+	Container     string         `json:"container"`
+	PreviousImage string         `json:"previousImage"`
+	Image         string         `json:"image"`
+	Digest        string         `json:"digest,omitempty"`
+	Outcome       string         `json:"outcome"`
+	Ready         string         `json:"ready"`
+	Pods          []k8s.PodImage `json:"pods,omitempty"`
+}
+
+// validateOutput checks an -o value
+func validateOutput(output string) error {
+	format, _, _ := strings.Cut(output, "=")
+	switch format {
+	case "", "table", "wide", "json", "yaml",
+		"jsonpath", "jsonpath-file", "jsonpath-as-json", "go-template", "go-template-file":
+		return nil
+	}
+	return fmt.Errorf("invalid --output %q: must be table, wide, json, yaml, jsonpath=TEMPLATE or go-template=TEMPLATE", output)
+}
+
+// structuredOutput reports whether -o asks for machine readable output
+func structuredOutput(output string) bool {
+	return output != "" && output != "table" && output != "wide"
+}
+
+// printRows prints a table or, for structured formats, a list object through cli-runtime's printers
+func printRows(w io.Writer, output, kind string, table *metav1.Table, rows interface{}) error {
+	if !structuredOutput(output) {
+		return printers.NewTablePrinter(printers.PrintOptions{Wide: output == "wide"}).PrintObj(table, w)
+	}
+
+	// jsonpath and go-template printers take their template after "=", like kubectl get
+	printFlags := genericclioptions.NewPrintFlags("")
+	*printFlags.OutputFormat = output
+
+	printer, err := printFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	list, err := toList(kind, rows)
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(list, w)
+}
+
+// toList wraps rows in an unstructured list object, so printers and templates can address .items
+func toList(kind string, rows interface{}) (*unstructured.Unstructured, error) {
+	wrapper := struct {
+		Items interface{} `json:"items"`
+	}{rows}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&wrapper)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build output: %v", err)
+	}
+
+	list := &unstructured.Unstructured{Object: content}
+	list.SetAPIVersion(outputAPIVersion)
+	list.SetKind(kind)
+	return list, nil
+}
+
+// readyCount returns the number of pods whose container is ready, as "ready/total"
+func readyCount(pods []k8s.PodImage, container string) (string, []k8s.PodImage) {
+	var matched []k8s.PodImage
+	ready := 0
+	for _, pod := range pods {
+		if pod.Container != container {
+			continue
+		}
+		matched = append(matched, pod)
+		if pod.Ready {
+			ready++
+		}
+	}
+	return fmt.Sprintf("%d/%d", ready, len(matched)), matched
+}
+
+// imageIDs formats the image IDs of pods as "pod=sha256:0123456789ab"
+func imageIDs(pods []k8s.PodImage) string {
+	var ids []string
+	for _, pod := range pods {
+		digest, _ := imageDigest(pod.ImageID)
+		id := imageref.ShortDigest(digest)
+		if id == "" {
+			id = "<pending>"
+		}
+		ids = append(ids, fmt.Sprintf("%s=%s", pod.Pod, id))
+	}
+	if len(ids) == 0 {
+		return "<none>"
+	}
+	return strings.Join(ids, ",")
+}
+
+// podImages lists the running images of a workload; pods are informational, so failures only warn
+func (o *SetImageOptions) podImages(workload k8s.Workload) []k8s.PodImage {
+	pods, err := o.k8sClient.GetPodImages(workload)
+	if err != nil {
+		fmt.Fprintf(o.log, "Warning: %v\n", err)
+	}
+	return pods
+}
+
+// printContainers prints the containers of the workloads for --list
+func (o *SetImageOptions) printContainers() error {
+	// Resolving tags needs the registry, so it is only done on request
+	var resolved map[string]string
+	if o.checkDrift {
+		resolved = make(map[string]string)
+	}
+	var rows []containerRow
+	for _, workload := range o.workloads {
+		containers, err := o.k8sClient.GetContainers(workload)
+		if err != nil {
+			return err
+		}
+
+		// Ephemeral containers live on running pods only; failing to list them is not fatal
+		ephemeralContainers, err := o.k8sClient.GetEphemeralContainers(workload)
+		if err != nil {
+			fmt.Fprintf(o.log, "Warning: %v\n", err)
+		}
+
+		pods := o.podImages(workload)
+		for _, container := range append(containers, ephemeralContainers...) {
+			ready, containerPods := readyCount(pods, container.Name)
+			drift := ""
+			if container.Type == k8s.ContainerTypeEphemeral {
+				ready, containerPods = "", nil
+			} else {
+				drift = o.tagDrift(container, pods, resolved)
+			}
+			rows = append(rows, containerRow{
+				Workload:  workload.String(),
+				Container: container.Name,
+				Type:      string(container.Type),
+				Image:     container.Image,
+				Pod:       container.Pod,
+				Ready:     ready,
+				Drift:     drift,
+				Pods:      containerPods,
+			})
+		}
+	}
+
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Workload", Type: "string"},
+			{Name: "Container", Type: "string"},
+			{Name: "Type", Type: "string"},
+			{Name: "Image", Type: "string"},
+			{Name: "Ready", Type: "string", Priority: 1},
+			{Name: "Image IDs", Type: "string", Priority: 1},
+		},
+	}
+	if o.checkDrift {
+		table.ColumnDefinitions = slices.Insert(table.ColumnDefinitions, 4, metav1.TableColumnDefinition{Name: "Drift", Type: "string"})
+	}
+	for _, row := range rows {
+		container := row.Container
+		if row.Pod != "" {
+			container = fmt.Sprintf("%s (pod %s)", row.Container, row.Pod)
+		}
+		cells := []interface{}{row.Workload, container, row.Type, row.Image, row.Ready, imageIDs(row.Pods)}
+		if o.checkDrift {
+			cells = slices.Insert(cells, 4, interface{}(orNone(row.Drift)))
+		}
+		table.Rows = append(table.Rows, metav1.TableRow{Cells: cells})
+	}
+
+	return printRows(os.Stdout, o.output, "ContainerList", table, rows)
+}
+
+// printUpdates prints the result of the image updates for -o
+func (o *SetImageOptions) printUpdates() error {
+	var rows []updateRow
+	for _, workload := range o.workloads {
+		pods := o.podImages(workload)
+		for _, name := range sortedKeys(o.images) {
+			ready, containerPods := readyCount(pods, name)
+			rows = append(rows, updateRow{
+				Workload:      workload.String(),
+				Container:     name,
+				PreviousImage: o.previousImages[workload][name],
+				Image:         o.images[name],
+				Digest:        o.changeInfo.Digests[name],
+				Outcome:       o.outcomes[workload],
+				Ready:         ready,
+				Pods:          containerPods,
+			})
+		}
+	}
+
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Workload", Type: "string"},
+			{Name: "Container", Type: "string"},
+			{Name: "Previous Image", Type: "string"},
+			{Name: "Image", Type: "string"},
+			{Name: "Digest", Type: "string"},
+			{Name: "Outcome", Type: "string"},
+			{Name: "Ready", Type: "string", Priority: 1},
+			{Name: "Image IDs", Type: "string", Priority: 1},
+		},
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, metav1.TableRow{
+			Cells: []interface{}{row.Workload, row.Container, row.PreviousImage, row.Image, orNone(row.Digest),
+				row.Outcome, row.Ready, imageIDs(row.Pods)},
+		})
+	}
+
+	return printRows(os.Stdout, o.output, "ImageUpdateList", table, rows)
+}
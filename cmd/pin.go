@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+)
+
+// pinRequired reports whether images must be pinned, by --pin-digest or because a workload
+// lives in a namespace matching --require-digest-namespaces
+func (o *SetImageOptions) pinRequired() (bool, error) {
+	if o.pinDigest {
+		return true, nil
+	}
+
+	for _, workload := range o.workloads {
+		namespace := workload.Namespace
+		if namespace == "" {
+			namespace = o.k8sClient.GetNamespace()
+		}
+		for _, pattern := range o.requireDigestNamespaces {
+			matched, err := path.Match(pattern, namespace)
+			if err != nil {
+				return false, fmt.Errorf("invalid --require-digest-namespaces pattern %q: %v", pattern, err)
+			}
+			if matched {
+				fmt.Fprintf(o.log, "📌 Namespace %s requires images pinned to digests\n", namespace)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// pinImages resolves the tags of the new images through the registry provider and writes them
+// as repo:tag@sha256:..., so the workload keeps running what was picked even if the tag moves
+func (o *SetImageOptions) pinImages() error {
+	if o.pinned {
+		return nil
+	}
+	o.pinned = true
+
+	required, err := o.pinRequired()
+	if err != nil || !required {
+		return err
+	}
+
+	for _, name := range sortedKeys(o.images) {
+		image := o.images[name]
+		ref, err := imageref.Parse(image)
+		if err != nil {
+			return err
+		}
+		if ref.Digest != "" {
+			continue
+		}
+
+		digest, err := o.registry.ResolveDigest(image)
+		if err != nil {
+			return fmt.Errorf("failed to pin %s to a digest: %v", image, err)
+		}
+		o.images[name] = ref.WithDigest(digest)
+		fmt.Fprintf(o.log, "📌 Pinned %s to %s\n", image, digest)
+	}
+	return nil
+}
+
+// tagDrift describes pods of a container that run another digest than its tag resolves to now,
+// or "" when there is no drift or it can't be checked
+func (o *SetImageOptions) tagDrift(container k8s.ContainerInfo, pods []k8s.PodImage, resolved map[string]string) string {
+	current, compared, moved := o.movedTagPods(container.Name, container.Image, pods, resolved)
+	if len(moved) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("tag now %s, %d/%d pods differ", imageref.ShortDigest(current), len(moved), compared)
+}
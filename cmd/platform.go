@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+	"github.com/tkuchiki/kubectl-setimg/pkg/registry"
+)
+
+// Policies of --platform-check
+const (
+	platformCheckWarn  = "warn"
+	platformCheckBlock = "block"
+	platformCheckOff   = "off"
+)
+
+// maxListedNodes is the number of node names shown in a platform mismatch
+const maxListedNodes = 3
+
+// checkPlatforms compares the platforms the new images are built for with the nodes each workload
+// can be scheduled on, so an amd64-only image doesn't end up with exec format errors on arm64 nodes
+func (o *SetImageOptions) checkPlatforms() error {
+	if o.platformCheck == platformCheckOff {
+		return nil
+	}
+
+	imagePlatforms := make(map[string][]registry.Platform)
+	for _, name := range sortedKeys(o.images) {
+		platforms, err := o.registry.ImagePlatforms(o.images[name])
+		if err != nil {
+			fmt.Fprintf(o.log, "Warning: can't check platforms of %s: %v\n", o.images[name], err)
+			continue
+		}
+		if len(platforms) > 0 {
+			imagePlatforms[name] = platforms
+		}
+	}
+	if len(imagePlatforms) == 0 {
+		return nil
+	}
+
+	var mismatches []string
+	for _, workload := range o.workloads {
+		nodePlatforms, err := o.k8sClient.SchedulablePlatforms(workload)
+		if err != nil {
+			fmt.Fprintf(o.log, "Warning: can't check node platforms of %s: %v\n", workload, err)
+			continue
+		}
+
+		for _, name := range sortedKeys(o.images) {
+			platforms, ok := imagePlatforms[name]
+			if !ok {
+				continue
+			}
+			for _, node := range nodePlatforms {
+				if !supportsNode(platforms, node) {
+					mismatches = append(mismatches, fmt.Sprintf("%s container %s: %s has no %s image (built for %s), but the pods can be scheduled on %s",
+						workload, name, o.images[name], node, platformList(platforms), nodeList(node.Nodes)))
+				}
+			}
+		}
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	for _, mismatch := range mismatches {
+		fmt.Fprintf(o.log, "⚠️  %s\n", mismatch)
+	}
+	if o.platformCheck == platformCheckBlock {
+		return fmt.Errorf("image platforms don't match the schedulable nodes (use --platform-check=warn to update anyway)")
+	}
+	return nil
+}
+
+// supportsNode reports whether any image platform runs on the nodes of a platform
+func supportsNode(platforms []registry.Platform, node k8s.NodePlatform) bool {
+	for _, platform := range platforms {
+		if platform.OS == node.OS && platform.Architecture == node.Architecture {
+			return true
+		}
+	}
+	return false
+}
+
+// platformList formats image platforms as "linux/amd64, linux/arm64/v8"
+func platformList(platforms []registry.Platform) string {
+	names := make([]string, len(platforms))
+	for i, platform := range platforms {
+		names[i] = platform.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// nodeList formats node names, shortening long lists as "a, b, c and 4 more nodes"
+func nodeList(nodes []string) string {
+	if len(nodes) <= maxListedNodes {
+		return strings.Join(nodes, ", ")
+	}
+	return fmt.Sprintf("%s and %d more nodes", strings.Join(nodes[:maxListedNodes], ", "), len(nodes)-maxListedNodes)
+}
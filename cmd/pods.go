@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+	"github.com/tkuchiki/kubectl-setimg/pkg/tui"
+)
+
+// podGroup is a set of pods running a container from the same digest and ReplicaSet
+type podGroup struct {
+	Revision string   `json:"revision,omitempty"`
+	Digest   string   `json:"digest"`
+	Image    string   `json:"image"`
+	Pods     []string `json:"pods"`
+	Ready    int      `json:"ready"`
+}
+
+// containerPods is the running state of a container across the pods of a workload
+type containerPods struct {
+	Workload  string     `json:"workload"`
+	Container string     `json:"container"`
+	Image     string     `json:"image"`
+	Groups    []podGroup `json:"groups"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// imageDigest extracts the digest from a container status imageID. repoDigest is false when the
+// runtime only reported a local image ID, which can't be compared with registry digests.
+func imageDigest(imageID string) (digest string, repoDigest bool) {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:], true
+	}
+	return imageID, false
+}
+
+// sameImage reports whether two image references name the same image. References that don't
+// parse, like the bare image IDs some runtimes report, are not flagged.
+func sameImage(a, b string) bool {
+	refA, errA := imageref.Parse(a)
+	refB, errB := imageref.Parse(b)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return refA.SameImage(refB)
+}
+
+// tagOf returns the tag an image follows without its digest, or "" when it can't move
+func tagOf(image string) string {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return ""
+	}
+	return ref.Tagged()
+}
+
+// inspectPods groups the pods running a container and flags states that need attention:
+// several digests at once, pods whose status disagrees with their spec, pods still on an older
+// template, and a tag that now resolves to a different digest than the pods run.
+func (o *SetImageOptions) inspectPods(workload k8s.Workload, container, templateImage string, pods []k8s.PodImage, resolved map[string]string) containerPods {
+	result := containerPods{
+		Workload:  workload.String(),
+		Container: container,
+		Image:     templateImage,
+	}
+
+	groups := make(map[[2]string]*podGroup)
+	digests := make(map[string]bool)
+	var outdated []string
+	for _, pod := range pods {
+		if pod.Container != container {
+			continue
+		}
+
+		digest, _ := imageDigest(pod.ImageID)
+		if digest != "" {
+			digests[digest] = true
+		}
+
+		key := [2]string{pod.Revision, digest}
+		group, ok := groups[key]
+		if !ok {
+			group = &podGroup{Revision: pod.Revision, Digest: digest, Image: pod.Image}
+			groups[key] = group
+		}
+		group.Pods = append(group.Pods, pod.Pod)
+		if pod.Ready {
+			group.Ready++
+		}
+
+		if pod.Image != "" && pod.SpecImage != "" && !sameImage(pod.Image, pod.SpecImage) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("spec/status mismatch: pod %s runs %s but its spec has %s",
+				pod.Pod, pod.Image, pod.SpecImage))
+		}
+		if pod.SpecImage != templateImage {
+			outdated = append(outdated, pod.Pod)
+		}
+	}
+
+	for _, group := range groups {
+		result.Groups = append(result.Groups, *group)
+	}
+	sort.Slice(result.Groups, func(i, j int) bool {
+		if result.Groups[i].Revision != result.Groups[j].Revision {
+			return result.Groups[i].Revision < result.Groups[j].Revision
+		}
+		return result.Groups[i].Digest < result.Groups[j].Digest
+	})
+
+	if len(digests) > 1 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("mixed versions: %d digests are running", len(digests)))
+	}
+	if len(outdated) > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d pods still run an older template than %s: %s",
+			len(outdated), templateImage, strings.Join(outdated, ", ")))
+	}
+
+	// A mutable tag that moved means restarted pods would pull something else
+	current, _, moved := o.movedTagPods(container, templateImage, pods, resolved)
+	for _, pod := range moved {
+		digest, _ := imageDigest(pod.ImageID)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("tag moved: %s now points to %s, pod %s runs %s",
+			tagOf(templateImage), imageref.ShortDigest(current), pod.Pod, imageref.ShortDigest(digest)))
+	}
+
+	return result
+}
+
+// movedTagPods resolves what the tag of a container's image points to now and compares it with
+// the pods running that image by repo digest. It returns the current digest, the number of pods
+// compared and those running another digest; nothing is compared when the tag can't be resolved.
+func (o *SetImageOptions) movedTagPods(container, image string, pods []k8s.PodImage, resolved map[string]string) (string, int, []k8s.PodImage) {
+	current := o.resolveCurrentDigest(image, resolved)
+	if current == "" {
+		return "", 0, nil
+	}
+
+	compared := 0
+	var moved []k8s.PodImage
+	for _, pod := range pods {
+		digest, repoDigest := imageDigest(pod.ImageID)
+		if pod.Container != container || pod.SpecImage != image || !repoDigest {
+			continue
+		}
+		compared++
+		if digest != current {
+			moved = append(moved, pod)
+		}
+	}
+	return current, compared, moved
+}
+
+// resolveCurrentDigest resolves what the tag of an image points to now, caching results in resolved.
+// Images pinned to a digest without a tag can't move and resolve to "". A nil resolved skips the
+// registry lookup, so views that don't ask for moved tags work without registry access.
+func (o *SetImageOptions) resolveCurrentDigest(image string, resolved map[string]string) string {
+	tagged := tagOf(image)
+	if tagged == "" || resolved == nil {
+		return ""
+	}
+
+	if digest, ok := resolved[tagged]; ok {
+		return digest
+	}
+	digest, err := o.registry.ResolveDigest(tagged)
+	if err != nil {
+		fmt.Fprintf(o.log, "Warning: can't check whether %s moved: %v\n", tagged, err)
+	}
+	resolved[tagged] = digest
+	return digest
+}
+
+// workloadPods inspects the running pods of every container in a workload's pod template
+func (o *SetImageOptions) workloadPods(workload k8s.Workload, resolved map[string]string) ([]containerPods, error) {
+	containers, err := o.k8sClient.GetContainers(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := o.podImages(workload)
+	var result []containerPods
+	for _, container := range containers {
+		result = append(result, o.inspectPods(workload, container.Name, container.Image, pods, resolved))
+	}
+	return result, nil
+}
+
+// printPods prints the running pods of the workloads for --list --pods
+func (o *SetImageOptions) printPods() error {
+	resolved := make(map[string]string)
+	var items []containerPods
+	for _, workload := range o.workloads {
+		containers, err := o.workloadPods(workload, resolved)
+		if err != nil {
+			return err
+		}
+		items = append(items, containers...)
+	}
+
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Workload", Type: "string"},
+			{Name: "Container", Type: "string"},
+			{Name: "Revision", Type: "string"},
+			{Name: "Digest", Type: "string"},
+			{Name: "Ready", Type: "string"},
+			{Name: "Image", Type: "string"},
+			{Name: "Pods", Type: "string", Priority: 1},
+		},
+	}
+	for _, item := range items {
+		for _, group := range item.Groups {
+			table.Rows = append(table.Rows, metav1.TableRow{
+				Cells: []interface{}{item.Workload, item.Container, orNone(group.Revision), orNone(imageref.ShortDigest(group.Digest)),
+					fmt.Sprintf("%d/%d", group.Ready, len(group.Pods)), group.Image, strings.Join(group.Pods, ",")},
+			})
+		}
+	}
+
+	if err := printRows(os.Stdout, o.output, "ContainerPodsList", table, items); err != nil {
+		return err
+	}
+
+	// Structured formats carry the warnings in each item
+	if !structuredOutput(o.output) {
+		for _, item := range items {
+			for _, warning := range item.Warnings {
+				fmt.Printf("⚠️  %s %s: %s\n", item.Workload, item.Container, warning)
+			}
+		}
+	}
+	return nil
+}
+
+// tuiPodGroups converts the running state of a container for the TUI detail pane
+func tuiPodGroups(pods containerPods) ([]tui.PodGroup, []string) {
+	groups := make([]tui.PodGroup, len(pods.Groups))
+	for i, group := range pods.Groups {
+		groups[i] = tui.PodGroup{
+			Revision: group.Revision,
+			Digest:   imageref.ShortDigest(group.Digest),
+			Image:    group.Image,
+			Pods:     group.Pods,
+			Ready:    group.Ready,
+		}
+	}
+	return groups, pods.Warnings
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
+	"github.com/tkuchiki/kubectl-setimg/pkg/tui"
+)
+
+type RollbackOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	k8sClient   *k8s.Client
+
+	workload   k8s.Workload
+	revisions  []k8s.Revision
+	toRevision int64
+
+	// Flags
+	listOnly bool
+	reason   string
+}
+
+func NewRollbackOptions(configFlags *genericclioptions.ConfigFlags) *RollbackOptions {
+	return &RollbackOptions{
+		configFlags: configFlags,
+	}
+}
+
+func (o *RollbackOptions) Complete(args []string) error {
+	var err error
+	o.k8sClient, err = k8s.NewClient(o.configFlags)
+	if err != nil {
+		return err
+	}
+
+	o.k8sClient.SetChangeInfo(changeInfo(o.k8sClient, o.configFlags, o.reason))
+
+	if len(args) > 1 {
+		return fmt.Errorf("rollback takes a single deployment")
+	}
+	if len(args) == 0 {
+		return o.selectDeployment()
+	}
+
+	o.workload, err = resolveDeployment(o.k8sClient, args[0])
+	return err
+}
+
+// resolveDeployment accepts NAME or TYPE/NAME, as the root command does
+func resolveDeployment(client *k8s.Client, arg string) (k8s.Workload, error) {
+	if !strings.Contains(arg, "/") {
+		return k8s.Workload{Kind: k8s.KindDeployment, Name: arg}, nil
+	}
+
+	workloads, err := client.ResolveWorkloads([]string{arg}, &resource.FilenameOptions{})
+	if err != nil {
+		return k8s.Workload{}, err
+	}
+	if workloads[0].Kind != k8s.KindDeployment {
+		return k8s.Workload{}, fmt.Errorf("%s: only deployments keep ReplicaSet revisions", workloads[0])
+	}
+	return workloads[0], nil
+}
+
+// selectDeployment lets the user pick a deployment when none was given
+func (o *RollbackOptions) selectDeployment() error {
+	if !isTerminal() {
+		return fmt.Errorf("a deployment is required without a terminal")
+	}
+
+	fmt.Println("🚀 Loading deployments...")
+	workloads, err := o.k8sClient.ListWorkloads()
+	if err != nil {
+		return err
+	}
+
+	var tuiWorkloads []tui.WorkloadInfo
+	for _, w := range workloads {
+		if w.Kind != k8s.KindDeployment {
+			continue
+		}
+		tuiWorkloads = append(tuiWorkloads, tui.WorkloadInfo{
+			Kind:    string(w.Kind),
+			Name:    w.Name,
+			Summary: w.Summary,
+		})
+	}
+	if len(tuiWorkloads) == 0 {
+		return fmt.Errorf("no deployments found")
+	}
+
+	selected, err := tui.SelectWorkload(tuiWorkloads)
+	if err != nil {
+		return fmt.Errorf("failed to select deployment: %v", err)
+	}
+	o.workload = k8s.Workload{Kind: k8s.KindDeployment, Name: selected.Name}
+	return nil
+}
+
+func (o *RollbackOptions) Run() error {
+	var err error
+	o.revisions, err = o.k8sClient.ListRevisions(o.workload)
+	if err != nil {
+		return err
+	}
+	if len(o.revisions) == 0 {
+		return fmt.Errorf("no revisions found for %s", o.workload)
+	}
+
+	if o.listOnly {
+		o.printRevisions()
+		return nil
+	}
+
+	revision := o.toRevision
+	if revision == 0 {
+		revision, err = o.chooseRevision()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := o.k8sClient.RollbackToRevision(o.workload, revision); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s rolled back to revision %d\n", o.workload, revision)
+	return nil
+}
+
+// chooseRevision picks a revision in the TUI, or the previous one without a terminal
+// like kubectl rollout undo
+func (o *RollbackOptions) chooseRevision() (int64, error) {
+	if !isTerminal() {
+		for _, revision := range o.revisions {
+			if !revision.Current {
+				return revision.Number, nil
+			}
+		}
+		return 0, fmt.Errorf("no previous revision of %s to roll back to", o.workload)
+	}
+
+	tuiRevisions := make([]tui.RevisionInfo, len(o.revisions))
+	for i, r := range o.revisions {
+		tuiRevisions[i] = tui.RevisionInfo{
+			Number:      r.Number,
+			Images:      revisionImages(r),
+			ChangeCause: r.ChangeCause,
+			CreatedAt:   r.CreatedAt,
+			Current:     r.Current,
+		}
+	}
+
+	selected, err := tui.SelectRevision(tuiRevisions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select revision: %v", err)
+	}
+	if selected.Current {
+		return 0, fmt.Errorf("revision %d is already the current revision of %s", selected.Number, o.workload)
+	}
+	return selected.Number, nil
+}
+
+func (o *RollbackOptions) printRevisions() {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tREPLICASET\tAGE\tIMAGES\tCHANGE-CAUSE")
+	for _, r := range o.revisions {
+		number := fmt.Sprintf("%d", r.Number)
+		if r.Current {
+			number += "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			number, r.ReplicaSet, duration.HumanDuration(time.Since(r.CreatedAt)), revisionImages(r), r.ChangeCause)
+	}
+	tw.Flush()
+}
+
+// revisionImages formats the images of a revision as "container=image" pairs
+func revisionImages(r k8s.Revision) string {
+	pairs := make([]string, len(r.Containers))
+	for i, c := range r.Containers {
+		pairs[i] = fmt.Sprintf("%s=%s", c.Name, c.Image)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func NewRollbackCommand(configFlags *genericclioptions.ConfigFlags) *cobra.Command {
+	opts := NewRollbackOptions(configFlags)
+
+	cmd := &cobra.Command{
+		Use:   "rollback [DEPLOYMENT | deployment/NAME]",
+		Short: "Roll a deployment back to an earlier ReplicaSet revision",
+		Long: `Roll a deployment back to any earlier ReplicaSet revision.
+
+Revisions are listed with the images of each container, their change-cause and age.
+The chosen revision's pod template is restored like kubectl rollout undo --to-revision.
+Without --to-revision a revision is picked interactively, or the previous one is used
+when there is no terminal.`,
+		Example: `  # Pick a revision interactively
+  kubectl setimg rollback my-app
+
+  # List revisions with their images
+  kubectl setimg rollback my-app --list
+
+  # Restore a specific revision
+  kubectl setimg rollback my-app --to-revision=3`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+			return opts.Run()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.listOnly, "list", "l", false, "List revisions only")
+	cmd.Flags().StringVar(&opts.reason, "reason", "", "Reason for the rollback, recorded in the deployment annotations")
+	cmd.Flags().Int64Var(&opts.toRevision, "to-revision", 0, "The revision to roll back to (0 picks one interactively, or the previous revision without a terminal)")
+
+	return cmd
+}
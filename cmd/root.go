@@ -1,36 +1,84 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
 
 	"github.com/tkuchiki/kubectl-setimg/pkg/k8s"
 	"github.com/tkuchiki/kubectl-setimg/pkg/registry"
 	"github.com/tkuchiki/kubectl-setimg/pkg/tui"
 )
 
+// Rollback policies for --rollback
+const (
+	rollbackAuto   = "auto"
+	rollbackPrompt = "prompt"
+	rollbackNever  = "never"
+)
+
 type SetImageOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 	k8sClient   *k8s.Client
 	registry    *registry.Client
 
-	deployment string
-	container  string
-	image      string
+	filenames resource.FilenameOptions
+	workloads []k8s.Workload
+	container string
+	images    map[string]string
 
 	// Flags
-	listOnly     bool
-	watchMode    bool
-	version      bool
-	watchTimeout time.Duration
+	output         string
+	listOnly       bool
+	listPods       bool
+	checkDrift     bool
+	watchMode      bool
+	version        bool
+	watchTimeout   time.Duration
+	serverSide     bool
+	forceConflicts bool
+	fieldManager   string
+	reason         string
+	pinDigest      bool
+	skipVerify     bool
+	platformCheck  string
+	harborHosts    []string
+	dryRun         string
+	diff           bool
+	changeInfo     k8s.ChangeInfo
+
+	// Namespaces (glob patterns) where images must be pinned to digests
+	requireDigestNamespaces []string
+
+	// pinned is set once pinImages ran, as interactive mode pins before the confirm screen
+	pinned bool
+
+	// reviewed is set once the pre-flight checks passed and the diff was confirmed in interactive mode
+	reviewed bool
+
+	// log receives progress messages; stderr when -o asks for machine readable output
+	log      io.Writer
+	outcomes map[k8s.Workload]string
+
+	// Failure detection for watch mode
+	failureRulesFile string
+	maxRestarts      int32
+	fatalReasons     []string
+	notReadyTimeout  time.Duration
 
 	// For rollback
-	previousImage string
+	rollback       string
+	previousImages map[k8s.Workload]map[string]string
+	baselines      map[k8s.Workload]k8s.RestartBaseline
 }
 
 func NewSetImageOptions() *SetImageOptions {
@@ -38,10 +86,26 @@ func NewSetImageOptions() *SetImageOptions {
 		configFlags:  genericclioptions.NewConfigFlags(true),
 		registry:     registry.NewClient(),
 		watchTimeout: 5 * time.Minute,
+		rollback:     rollbackPrompt,
+		dryRun:       dryRunNone,
+		log:          os.Stdout,
 	}
 }
 
-func (o *SetImageOptions) Complete(args []string) error {
+func (o *SetImageOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := validateOutput(o.output); err != nil {
+		return err
+	}
+	if structuredOutput(o.output) {
+		o.log = os.Stderr
+	}
+	if o.listPods && !o.listOnly {
+		return fmt.Errorf("--pods only works with --list")
+	}
+	if o.checkDrift && !o.listOnly {
+		return fmt.Errorf("--check-drift only works with --list")
+	}
+
 	// Initialize Kubernetes client
 	var err error
 	o.k8sClient, err = k8s.NewClient(o.configFlags)
@@ -49,117 +113,248 @@ func (o *SetImageOptions) Complete(args []string) error {
 		return err
 	}
 
+	switch o.rollback {
+	case rollbackAuto, rollbackPrompt, rollbackNever:
+	default:
+		return fmt.Errorf("invalid --rollback %q: must be auto, prompt or never", o.rollback)
+	}
+
+	switch o.dryRun {
+	case dryRunNone, dryRunClient, dryRunServer:
+	default:
+		return fmt.Errorf("invalid --dry-run %q: must be none, client or server", o.dryRun)
+	}
+	if o.dryRun != dryRunNone && o.watchMode {
+		return fmt.Errorf("--watch cannot be used with --dry-run")
+	}
+
+	switch o.platformCheck {
+	case platformCheckWarn, platformCheckBlock, platformCheckOff:
+	default:
+		return fmt.Errorf("invalid --platform-check %q: must be warn, block or off", o.platformCheck)
+	}
+
+	// Harbor runs on any host, so its provider only handles the configured ones
+	if len(o.harborHosts) > 0 {
+		o.registry.AddProvider(registry.NewHarborProvider(o.harborHosts))
+	}
+
+	if o.forceConflicts && !o.serverSide {
+		return fmt.Errorf("--force-conflicts only works with --server-side")
+	}
+	o.k8sClient.SetUpdateOptions(k8s.UpdateOptions{
+		ServerSide:     o.serverSide,
+		ForceConflicts: o.forceConflicts,
+		FieldManager:   o.fieldManager,
+	})
+
+	o.changeInfo = changeInfo(o.k8sClient, o.configFlags, o.reason)
+	o.k8sClient.SetChangeInfo(o.changeInfo)
+
+	if err := o.completeFailureRules(cmd); err != nil {
+		return err
+	}
+
+	resources, pairs := splitArgs(args)
+
 	// Auto-detect interactive mode based on missing information
-	// If any required information is missing and not in list mode, use interactive selection
-	if !o.listOnly {
-		shouldUseInteractive := false
+	// If container=image is missing and not in list mode, use interactive selection
+	if !o.listOnly && len(pairs) == 0 {
+		fmt.Fprintln(o.log, "🎯 Missing required information, switching to interactive mode...")
 
-		// Check if deployment is missing
-		if len(args) < 1 {
-			shouldUseInteractive = true
-		} else {
-			// Check if container=image is missing or incomplete
-			if len(args) < 2 {
-				shouldUseInteractive = true
-			} else {
-				// Check if the second argument is in container=image format
-				containerImagePair := args[1]
-				parts := strings.Split(containerImagePair, "=")
-				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-					shouldUseInteractive = true
-				}
-			}
+		resources, o.container = o.splitContainerArg(resources)
+		if err := o.resolveWorkloads(resources); err != nil {
+			return err
 		}
-
-		// For interactive mode, process deployment and container names if provided
-		if shouldUseInteractive {
-			fmt.Println("🎯 Missing required information, switching to interactive mode...")
-			if len(args) >= 1 {
-				o.deployment = args[0]
-			}
-			if len(args) >= 2 {
-				// In interactive mode, only container name can be specified (not container=image)
-				o.container = args[1]
-			}
-			// Run interactive mode directly in Complete
-			return o.runInteractiveMode()
+		if len(o.workloads) > 1 {
+			return fmt.Errorf("interactive mode supports a single workload, got %d", len(o.workloads))
 		}
+
+		// Run interactive mode directly in Complete
+		return o.runInteractiveMode()
 	}
 
-	// For list mode, only deployment name is required
+	if err := o.resolveWorkloads(resources); err != nil {
+		return err
+	}
+	if len(o.workloads) == 0 {
+		return fmt.Errorf("a workload (NAME, TYPE/NAME or -f FILENAME) is required")
+	}
+
+	// For list mode, only the workloads are required
 	if o.listOnly {
-		if len(args) < 1 {
-			return fmt.Errorf("deployment name is required for --list mode")
-		}
-		o.deployment = args[0]
 		return nil
 	}
 
-	// Direct mode: require both deployment and container=image
-	if len(args) < 1 {
-		return fmt.Errorf("deployment name is required")
+	// Direct mode: require one or more container=image pairs
+	o.images = make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("container=image format required for direct mode: %s", pair)
+		}
+		if _, ok := o.images[parts[0]]; ok {
+			return fmt.Errorf("container %s specified more than once", parts[0])
+		}
+		o.images[parts[0]] = parts[1]
 	}
-	o.deployment = args[0]
 
-	if len(args) < 2 {
-		return fmt.Errorf("container=image is required for direct mode")
+	return nil
+}
+
+// completeFailureRules builds the watch mode failure rules from the defaults,
+// the --failure-rules file and the individual flags, in that order
+func (o *SetImageOptions) completeFailureRules(cmd *cobra.Command) error {
+	rules := k8s.DefaultFailureRules()
+	if o.failureRulesFile != "" {
+		var err error
+		rules, err = k8s.LoadFailureRules(o.failureRulesFile)
+		if err != nil {
+			return err
+		}
 	}
 
-	containerImagePair := args[1]
-	parts := strings.Split(containerImagePair, "=")
-	if len(parts) != 2 {
-		return fmt.Errorf("container=image format required for direct mode")
+	if cmd.Flags().Changed("max-restarts") {
+		rules.MaxRestarts = o.maxRestarts
+	}
+	rules.FatalReasons = append(rules.FatalReasons, o.fatalReasons...)
+	if cmd.Flags().Changed("not-ready-timeout") {
+		rules.NotReadyTimeout = o.notReadyTimeout
 	}
-	o.container = parts[0]
-	o.image = parts[1]
 
+	o.k8sClient.SetFailureRules(rules)
 	return nil
 }
 
-func (o *SetImageOptions) listContainers() error {
-	containers, err := o.k8sClient.GetContainers(o.deployment)
-	if err != nil {
-		return err
+// listFromEnv returns the values of a comma-separated environment variable, used as flag defaults
+// so settings like policies apply without being typed on every invocation
+func listFromEnv(variable string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(variable), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// splitArgs separates container=image pairs from resource arguments
+func splitArgs(args []string) (resources, pairs []string) {
+	for _, arg := range args {
+		if strings.Contains(arg, "=") {
+			pairs = append(pairs, arg)
+		} else {
+			resources = append(resources, arg)
+		}
 	}
+	return resources, pairs
+}
 
-	fmt.Printf("Containers in deployment %s:\n", o.deployment)
-	fmt.Println("INDEX\tNAME\t\tCURRENT IMAGE")
-	fmt.Println("-----\t----\t\t-------------")
-	for i, container := range containers {
-		fmt.Printf("%d\t%s\t\t%s\n", i+1, container.Name, container.Image)
+// splitContainerArg splits off the trailing bare argument that names the container in interactive
+// mode (not container=image). TYPE NAME is read first, like the resource builder does, so
+// "deployment web" selects the deployment web, while "my-app web" and "deployment my-app web"
+// select the container web of the deployment my-app.
+func (o *SetImageOptions) splitContainerArg(resources []string) ([]string, string) {
+	n := len(resources)
+	if n == 0 || strings.Contains(resources[n-1], "/") {
+		return resources, ""
 	}
 
-	return nil
+	// The number of arguments naming the workload before a container can follow
+	workloadArgs := 1
+	switch {
+	case o.hasFilenames():
+		workloadArgs = 0
+	case !strings.Contains(resources[0], "/") && o.k8sClient.IsResourceType(resources[0]):
+		workloadArgs = 2
+	}
+	if n <= workloadArgs {
+		return resources, ""
+	}
+	return resources[:n-1], resources[n-1]
+}
+
+// hasFilenames reports whether workloads are given through -f or -k
+func (o *SetImageOptions) hasFilenames() bool {
+	return len(o.filenames.Filenames) > 0 || o.filenames.Kustomize != ""
+}
+
+// resolveWorkloads turns resource arguments and -f manifests into workloads
+func (o *SetImageOptions) resolveWorkloads(resources []string) error {
+	if len(resources) == 0 && !o.hasFilenames() {
+		return nil
+	}
+
+	// A single bare name is a deployment, for compatibility with earlier releases
+	if len(resources) == 1 && !strings.Contains(resources[0], "/") && !o.hasFilenames() {
+		o.workloads = []k8s.Workload{{Kind: k8s.KindDeployment, Name: resources[0]}}
+		return nil
+	}
+
+	var err error
+	o.workloads, err = o.k8sClient.ResolveWorkloads(resources, &o.filenames)
+	return err
 }
 
 func (o *SetImageOptions) runInteractiveMode() error {
 	var err error
 
-	// 1. Select deployment
-	if o.deployment == "" {
-		fmt.Println("🚀 Loading deployments...")
-		o.deployment, err = tui.SelectDeployment(o.k8sClient.GetClientset(), o.k8sClient.GetNamespace())
+	// 1. Select workload
+	var workload k8s.Workload
+	if len(o.workloads) == 0 {
+		fmt.Fprintln(o.log, "🚀 Loading workloads...")
+		workloads, err := o.k8sClient.ListWorkloads()
+		if err != nil {
+			return err
+		}
+
+		// Convert k8s.WorkloadInfo to tui.WorkloadInfo
+		tuiWorkloads := make([]tui.WorkloadInfo, len(workloads))
+		for i, w := range workloads {
+			tuiWorkloads[i] = tui.WorkloadInfo{
+				Kind:    string(w.Kind),
+				Name:    w.Name,
+				Summary: w.Summary,
+			}
+		}
+
+		selectedWorkload, err := tui.SelectWorkload(tuiWorkloads)
 		if err != nil {
-			return fmt.Errorf("failed to select deployment: %v", err)
+			return fmt.Errorf("failed to select workload: %v", err)
+		}
+		workload = k8s.Workload{
+			Kind: k8s.WorkloadKind(selectedWorkload.Kind),
+			Name: selectedWorkload.Name,
 		}
+		o.workloads = []k8s.Workload{workload}
+	} else {
+		workload = o.workloads[0]
 	}
 
 	// 2. Select container
 	var selectedContainer tui.ContainerInfo
 	if o.container == "" {
-		fmt.Println("📦 Loading containers...")
-		containers, err := o.k8sClient.GetContainers(o.deployment)
+		fmt.Fprintln(o.log, "📦 Loading containers...")
+		containers, err := o.k8sClient.GetContainers(workload)
 		if err != nil {
 			return err
 		}
 
+		// Running pods are shown in the detail pane of the container picker
+		// Moved tags are left to --list --pods, so the picker doesn't wait for the registry
+		pods := o.podImages(workload)
+
 		// Convert k8s.ContainerInfo to tui.ContainerInfo
 		tuiContainers := make([]tui.ContainerInfo, len(containers))
 		for i, c := range containers {
+			groups, warnings := tuiPodGroups(o.inspectPods(workload, c.Name, c.Image, pods, nil))
 			tuiContainers[i] = tui.ContainerInfo{
-				Name:  c.Name,
-				Image: c.Image,
-				Index: c.Index,
+				Name:     c.Name,
+				Image:    c.Image,
+				Index:    c.Index,
+				Init:     c.Type == k8s.ContainerTypeInit,
+				Pods:     groups,
+				Warnings: warnings,
 			}
 		}
 
@@ -170,12 +365,12 @@ func (o *SetImageOptions) runInteractiveMode() error {
 		o.container = selectedContainer.Name
 	} else {
 		// Get container info if container name is specified
-		fmt.Printf("🚀 Using specified deployment: %s\n", o.deployment)
-		fmt.Printf("📦 Using specified container: %s\n", o.container)
+		fmt.Fprintf(o.log, "🚀 Using specified workload: %s\n", workload)
+		fmt.Fprintf(o.log, "📦 Using specified container: %s\n", o.container)
 
-		currentImage, err := o.k8sClient.GetCurrentImage(o.deployment, o.container)
+		currentImage, err := o.k8sClient.GetCurrentImage(workload, o.container)
 		if err != nil {
-			return fmt.Errorf("container %s not found in deployment %s: %v", o.container, o.deployment, err)
+			return fmt.Errorf("container %s not found in %s: %v", o.container, workload, err)
 		}
 		selectedContainer = tui.ContainerInfo{
 			Name:  o.container,
@@ -184,16 +379,18 @@ func (o *SetImageOptions) runInteractiveMode() error {
 	}
 
 	// 3. Select image tag
-	fmt.Println("🏷️  Loading image tags...")
+	fmt.Fprintln(o.log, "🏷️  Loading image tags...")
+
+	var image string
 
 	// Get tag list
 	tagInfos, err := o.registry.ListTagsWithInfo(selectedContainer.Image)
 	if err != nil {
-		fmt.Printf("⚠️  Failed to fetch tags: %v\n", err)
-		fmt.Println("📝 Falling back to manual input...")
+		fmt.Fprintf(o.log, "⚠️  Failed to fetch tags: %v\n", err)
+		fmt.Fprintln(o.log, "📝 Falling back to manual input...")
 
 		// Manual input if tag fetching fails
-		o.image, err = tui.InputCustomImage(selectedContainer.Image)
+		image, err = tui.InputCustomImage(selectedContainer.Image)
 		if err != nil {
 			return fmt.Errorf("failed to input image: %v", err)
 		}
@@ -202,61 +399,202 @@ func (o *SetImageOptions) runInteractiveMode() error {
 		tuiTagInfos := make([]tui.TagInfo, len(tagInfos))
 		for i, t := range tagInfos {
 			tuiTagInfos[i] = tui.TagInfo{
-				Tag:       t.Tag,
-				CreatedAt: t.CreatedAt,
+				Tag:           t.Tag,
+				CreatedAt:     t.CreatedAt,
+				Digest:        t.Digest,
+				Size:          t.Size,
+				Architectures: t.Architectures,
+				Details:       t.Details,
 			}
 		}
 
 		// Tag selection TUI
-		o.image, err = tui.SelectImageTagWithTimestamp(selectedContainer.Image, tuiTagInfos)
+		image, err = tui.SelectImageTagWithTimestamp(selectedContainer.Image, tuiTagInfos)
 		if err != nil {
 			return fmt.Errorf("failed to select image tag: %v", err)
 		}
 	}
 
-	// Confirmation message
-	fmt.Printf("\n✅ Selected:\n")
-	fmt.Printf("   Deployment: %s\n", o.deployment)
-	fmt.Printf("   Container:  %s\n", o.container)
-	fmt.Printf("   New Image:  %s\n", o.image)
-	fmt.Println()
+	o.images = map[string]string{o.container: image}
+	if err := o.pinImages(); err != nil {
+		return err
+	}
+
+	// A dry run shows the diff in RunWithPatch without applying anything
+	if o.dryRun != dryRunNone {
+		return nil
+	}
+
+	// Pre-flight checks run before the confirm screen, so a missing image or a platform
+	// mismatch is reported before the user accepts the diff
+	if err := o.preflight(); err != nil {
+		return err
+	}
+
+	// Confirm the change with a diff of the pod template; --diff computes it with a server-side
+	// dry run, so the confirmation shows what admission webhooks make of the change
+	diff, err := o.previewDiff(workload, o.diff)
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Update container %s of %s to %s?", o.container, workload, image)
+	if !tui.ConfirmUpdate(message, diff) {
+		return fmt.Errorf("update cancelled")
+	}
+	o.reviewed = true
 
 	return nil
 }
 
-func (o *SetImageOptions) savePreviousImage() error {
-	var err error
-	o.previousImage, err = o.k8sClient.GetCurrentImage(o.deployment, o.container)
-	return err
+func (o *SetImageOptions) savePreviousImages() error {
+	o.previousImages = make(map[k8s.Workload]map[string]string, len(o.workloads))
+	for _, workload := range o.workloads {
+		containers, err := o.k8sClient.GetContainers(workload)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[string]string, len(containers))
+		for _, c := range containers {
+			current[c.Name] = c.Image
+		}
+
+		previous := make(map[string]string, len(o.images))
+		for name := range o.images {
+			image, ok := current[name]
+			if !ok {
+				return fmt.Errorf("container %s not found in %s", name, workload)
+			}
+			previous[name] = image
+		}
+		o.previousImages[workload] = previous
+	}
+
+	// Restarts are counted from here so pods that were already flaky don't trigger a rollback
+	if o.watchMode {
+		o.baselines = make(map[k8s.Workload]k8s.RestartBaseline, len(o.workloads))
+		for _, workload := range o.workloads {
+			baseline, err := o.k8sClient.GetRestartBaseline(workload)
+			if err != nil {
+				return err
+			}
+			o.baselines[workload] = baseline
+		}
+	}
+	return nil
+}
+
+// preflight checks that the new images exist and run on the nodes of the workloads
+func (o *SetImageOptions) preflight() error {
+	if err := o.verifyImages(); err != nil {
+		return err
+	}
+	return o.checkPlatforms()
 }
 
 func (o *SetImageOptions) RunWithPatch() error {
-	// Save previous image before update
-	if err := o.savePreviousImage(); err != nil {
+	// Save previous images before update, so a missing container fails before anything is patched
+	if err := o.savePreviousImages(); err != nil {
 		return err
 	}
 
-	// Update the image
-	err := o.k8sClient.UpdateContainerImage(o.deployment, o.container, o.image)
-	if err != nil {
+	// Interactive mode ran the pre-flight checks before the confirm screen
+	if !o.reviewed {
+		if err := o.preflight(); err != nil {
+			return err
+		}
+	}
+
+	if err := o.pinImages(); err != nil {
 		return err
 	}
 
-	fmt.Printf("deployment.apps/%s container %s image updated to %s\n",
-		o.deployment, o.container, o.image)
+	// Show what would change; --diff previews with a server-side dry run like kubectl diff
+	if (o.diff || o.dryRun != dryRunNone) && !o.reviewed {
+		for _, workload := range o.workloads {
+			diff, err := o.previewDiff(workload, o.dryRun != dryRunClient)
+			if err != nil {
+				return err
+			}
+			if diff == "" {
+				fmt.Fprintf(o.log, "%s: no changes\n", workload)
+				continue
+			}
+			fmt.Fprint(o.log, tui.RenderDiff(diff))
+		}
+	}
+
+	o.outcomes = make(map[k8s.Workload]string, len(o.workloads))
+
+	if o.dryRun != dryRunNone {
+		for _, workload := range o.workloads {
+			o.outcomes[workload] = fmt.Sprintf("%s dry run", o.dryRun)
+			for _, name := range sortedKeys(o.images) {
+				fmt.Fprintf(o.log, "%s container %s image updated to %s (%s dry run)\n",
+					workload, name, o.images[name], o.dryRun)
+			}
+		}
+		return o.printResult(nil)
+	}
+
+	// Record what the new images resolve to, as tags can be moved later
+	o.changeInfo.Digests = o.resolveDigests()
+	o.k8sClient.SetChangeInfo(o.changeInfo)
+
+	// Update the images
+	for _, workload := range o.workloads {
+		err := o.k8sClient.UpdateContainerImages(workload, o.images)
+		if err != nil {
+			return err
+		}
+
+		o.outcomes[workload] = outcomeUpdated
+		for _, name := range sortedKeys(o.images) {
+			fmt.Fprintf(o.log, "%s container %s image updated to %s\n",
+				workload, name, o.images[name])
+		}
+	}
 
 	// Monitor pod status in watch mode
 	if o.watchMode {
-		return o.watchPodsAndRollbackIfNeeded()
+		return o.printResult(o.watchPodsAndRollbackIfNeeded())
 	}
 
-	return nil
+	return o.printResult(nil)
+}
+
+// printResult prints the update results when -o is given, and passes err through
+func (o *SetImageOptions) printResult(err error) error {
+	if o.output == "" {
+		return err
+	}
+	if printErr := o.printUpdates(); printErr != nil && err == nil {
+		return printErr
+	}
+	return err
+}
+
+// resolveDigests resolves the manifest digests of the new images, skipping those that fail
+func (o *SetImageOptions) resolveDigests() map[string]string {
+	digests := make(map[string]string, len(o.images))
+	for _, name := range sortedKeys(o.images) {
+		digest, err := o.registry.ResolveDigest(o.images[name])
+		if err != nil {
+			fmt.Fprintf(o.log, "Warning: digest of %s not recorded: %v\n", o.images[name], err)
+			continue
+		}
+		digests[name] = digest
+	}
+	return digests
 }
 
 func (o *SetImageOptions) Run() error {
 	// List only mode
 	if o.listOnly {
-		return o.listContainers()
+		if o.listPods {
+			return o.printPods()
+		}
+		return o.printContainers()
 	}
 
 	// Interactive mode already handled in Complete() method
@@ -266,78 +604,225 @@ func (o *SetImageOptions) Run() error {
 }
 
 func (o *SetImageOptions) watchPodsAndRollbackIfNeeded() error {
-	fmt.Printf("\n🔍 Watching deployment %s for %v...\n", o.deployment, o.watchTimeout)
+	var errs []error
+	exitCode := 0
+	for _, workload := range o.workloads {
+		fmt.Fprintf(o.log, "\n🔍 Watching %s for %v...\n", workload, o.watchTimeout)
+
+		err := o.k8sClient.WatchWorkload(workload, o.watchTimeout, o.baselines[workload], func(message string) {
+			fmt.Fprintf(o.log, "   %s\n", message)
+		})
+		if errors.Is(err, k8s.ErrPaused) {
+			// Nothing has rolled out, so there is nothing to roll back
+			fmt.Fprintf(o.log, "⏸️  %v\n", err)
+			o.outcomes[workload] = outcomePaused
+			continue
+		}
+		if errors.Is(err, k8s.ErrOnDelete) {
+			// The pods are replaced when they are deleted, so the update itself is fine
+			fmt.Fprintf(o.log, "⏭️  %v\n", err)
+			o.outcomes[workload] = outcomeUpdated
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(o.log, "❌ Error watching %s: %v\n", workload, err)
+			exitErr := o.rollbackWorkload(workload, err)
+			o.outcomes[workload] = exitOutcome(exitErr.Code)
+			errs = append(errs, exitErr.Err)
+			if exitErr.Code > exitCode {
+				exitCode = exitErr.Code
+			}
+			continue
+		}
 
-	err := o.k8sClient.WatchDeployment(o.deployment, o.watchTimeout)
-	if err != nil {
-		fmt.Printf("❌ Error watching deployment: %v\n", err)
-		return o.rollbackDeployment()
+		fmt.Fprintf(o.log, "✅ %s is ready!\n", workload)
+		o.outcomes[workload] = outcomeReady
 	}
 
-	fmt.Printf("✅ Deployment %s is ready!\n", o.deployment)
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ExitError{Code: exitCode, Err: errors.Join(errs...)}
+}
+
+// rollbackPolicy returns the effective rollback policy, downgrading prompt to auto without a terminal
+func (o *SetImageOptions) rollbackPolicy() string {
+	if o.rollback == rollbackPrompt && !isTerminal() {
+		fmt.Fprintln(o.log, "ℹ️  No terminal available to confirm, rolling back automatically (set --rollback to choose)")
+		return rollbackAuto
+	}
+	return o.rollback
 }
 
-func (o *SetImageOptions) rollbackDeployment() error {
-	if o.previousImage == "" {
-		return fmt.Errorf("no previous image saved for rollback")
+func (o *SetImageOptions) rollbackWorkload(workload k8s.Workload, cause error) *ExitError {
+	previousImages := o.previousImages[workload]
+	if len(previousImages) == 0 {
+		return exitErrorf(ExitRollbackFailed, "%s failed (%v) and no previous images were saved for rollback", workload, cause)
 	}
 
-	// Show confirmation screen in interactive mode
-	if o.deployment != "" && o.container != "" && o.image != "" {
-		message := fmt.Sprintf("Deployment failed. Rollback container %s to %s?", o.container, o.previousImage)
+	var changes []string
+	for _, name := range sortedKeys(previousImages) {
+		changes = append(changes, fmt.Sprintf("%s to %s", name, previousImages[name]))
+	}
+
+	switch o.rollbackPolicy() {
+	case rollbackNever:
+		fmt.Fprintln(o.log, "Rollback disabled by --rollback=never.")
+		return exitErrorf(ExitRollbackDeclined, "%s failed and was not rolled back: %v", workload, cause)
+
+	case rollbackPrompt:
+		message := fmt.Sprintf("%s failed. Rollback container %s?", workload, strings.Join(changes, ", "))
 		if !tui.ConfirmRollback(message) {
-			fmt.Println("Rollback cancelled by user.")
-			return fmt.Errorf("rollback cancelled")
+			fmt.Fprintln(o.log, "Rollback cancelled by user.")
+			return exitErrorf(ExitRollbackDeclined, "%s failed and rollback was declined: %v", workload, cause)
 		}
 	}
 
-	fmt.Printf("\n🔄 Rolling back container %s to previous image: %s\n", o.container, o.previousImage)
+	fmt.Fprintf(o.log, "\n🔄 Rolling back %s: container %s\n", workload, strings.Join(changes, ", "))
 
-	err := o.k8sClient.UpdateContainerImage(o.deployment, o.container, o.previousImage)
+	info := o.changeInfo
+	info.Reason = fmt.Sprintf("rollback by --watch: %v", cause)
+	info.Digests = nil
+	o.k8sClient.SetChangeInfo(info)
+
+	err := o.k8sClient.UpdateContainerImages(workload, previousImages)
 	if err != nil {
-		return fmt.Errorf("failed to rollback deployment: %v", err)
+		return exitErrorf(ExitRollbackFailed, "%s failed (%v) and rollback failed: %v", workload, cause, err)
 	}
 
-	fmt.Printf("✅ Rollback completed! Container %s image reverted to %s\n", o.container, o.previousImage)
-	return nil
+	for _, name := range sortedKeys(previousImages) {
+		fmt.Fprintf(o.log, "✅ Rollback completed! Container %s image reverted to %s\n", name, previousImages[name])
+	}
+	return exitErrorf(ExitRolledBack, "%s failed and was rolled back: %v", workload, cause)
+}
+
+// changeInfo returns the change details recorded in workload annotations
+func changeInfo(client *k8s.Client, configFlags *genericclioptions.ConfigFlags, reason string) k8s.ChangeInfo {
+	// SelfSubjectReview reports the user as the API server sees it, e.g. for OIDC or exec plugins
+	user, err := client.CurrentUser()
+	if err != nil || user == "" {
+		user = kubeconfigUser(configFlags)
+	}
+
+	return k8s.ChangeInfo{
+		User:    user,
+		Version: getVersion(),
+		Reason:  reason,
+	}
+}
+
+// kubeconfigUser returns the impersonated user, or the kubeconfig user of the current context,
+// for API servers that don't serve SelfSubjectReview
+func kubeconfigUser(configFlags *genericclioptions.ConfigFlags) string {
+	if configFlags.Impersonate != nil && *configFlags.Impersonate != "" {
+		return *configFlags.Impersonate
+	}
+	if configFlags.AuthInfoName != nil && *configFlags.AuthInfoName != "" {
+		return *configFlags.AuthInfoName
+	}
+
+	config, err := configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	contextName := config.CurrentContext
+	if configFlags.Context != nil && *configFlags.Context != "" {
+		contextName = *configFlags.Context
+	}
+	if context, ok := config.Contexts[contextName]; ok {
+		return context.AuthInfo
+	}
+	return ""
+}
+
+// isTerminal reports whether stdin and stdout are attached to a terminal
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// sortedKeys returns the keys of a container name map in a stable order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func NewRootCommand() *cobra.Command {
 	opts := NewSetImageOptions()
 
 	cmd := &cobra.Command{
-		Use:   "kubectl-setimg DEPLOYMENT [CONTAINER=IMAGE]",
-		Short: "Update container image in deployment with interactive selection",
-		Long: `Update container image in deployment with interactive selection and multi-registry support.
+		Use:   "kubectl-setimg (-f FILENAME | TYPE/NAME ... | DEPLOYMENT) [CONTAINER_1=IMAGE_1 ... CONTAINER_N=IMAGE_N]",
+		Short: "Update container image in a workload with interactive selection",
+		Long: `Update container image in a workload with interactive selection and multi-registry support.
+
+Supported workloads are Deployments, StatefulSets, DaemonSets, ReplicaSets and CronJobs. Job pod
+templates are immutable, so update the CronJob or recreate the Job instead.
 
 You can use this command in multiple ways:
 1. Interactive selection: kubectl setimg (automatically provides selection when arguments are omitted)
 2. Direct mode: kubectl setimg my-app web=nginx:1.21.1
 3. List containers: kubectl setimg my-app --list
-4. With automatic rollback: kubectl setimg my-app web=nginx:1.21.1 --watch`,
+4. With automatic rollback: kubectl setimg my-app web=nginx:1.21.1 --watch
+
+Resources use the same grammar as kubectl set image: a bare NAME is a deployment,
+TYPE/NAME selects any supported kind (deploy, sts, ds, rs, cronjob), several
+resources can be given at once, and -f reads them from manifests.
+
+Use "kubectl setimg rollback" to return a deployment to an earlier revision, and
+"kubectl setimg history" to see who changed its images and when. As these subcommands take
+precedence, workloads named rollback or history need the TYPE/NAME form (deployment/history).`,
 		Example: `  # Direct mode
   kubectl setimg my-app web=nginx:1.21.1
+
+  # Other workload kinds and several resources at once
+  kubectl setimg sts/db db=postgres:16
+  kubectl setimg deploy/web ds/agent app=myorg/app:v2
+  kubectl setimg deployment web worker app=myorg/app:v2
+  kubectl setimg -f manifest.yaml app=myorg/app:v2
+
+  # Several containers in one patch (a single new ReplicaSet)
+  kubectl setimg my-app app=myorg/app:v2 migrator=myorg/migrator:v2
+
+  # Preview the pod template diff, including admission webhook changes, without applying
+  kubectl setimg my-app web=nginx:1.21.1 --dry-run=server
+
+  # Show the diff and apply
+  kubectl setimg my-app web=nginx:1.21.1 --diff
+
+  # Server-side apply, failing if Helm or Argo CD own the image field
+  kubectl setimg my-app web=nginx:1.21.1 --server-side
   
   # Interactive selection - automatically triggered when arguments are missing
-  kubectl setimg                    # Select deployment, container, and image
+  kubectl setimg                    # Select workload, container, and image
   kubectl setimg my-app             # Select container and image
   kubectl setimg my-app web         # Select image only
+  kubectl setimg cronjob/nightly    # Select container and image
   
   # List containers only
   kubectl setimg my-app --list
   kubectl setimg my-app -l
+
+  # What the pods are actually running
+  kubectl setimg my-app --list --pods
   
   # Update with automatic rollback on failure
   kubectl setimg --watch
   kubectl setimg my-app web=nginx:1.21.1 --watch
-  kubectl setimg my-app web=nginx:1.21.1 --watch --timeout=10m`,
+  kubectl setimg my-app web=nginx:1.21.1 --watch --timeout=10m
+
+  # In CI: roll back without asking (exit code 3 when rolled back)
+  kubectl setimg my-app web=nginx:1.21.1 --watch --rollback=auto`,
+		// Positional arguments are resources, not subcommands
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if opts.version {
 				fmt.Println(GetVersionInfo())
 				return nil
 			}
-			if err := opts.Complete(args); err != nil {
+			if err := opts.Complete(cmd, args); err != nil {
 				return err
 			}
 			return opts.Run()
@@ -346,12 +831,37 @@ You can use this command in multiple ways:
 
 	// Add flags
 	cmd.Flags().BoolVarP(&opts.listOnly, "list", "l", false, "List containers only")
-	cmd.Flags().BoolVarP(&opts.watchMode, "watch", "w", false, "Watch deployment and rollback if pods fail to start")
-	cmd.Flags().DurationVar(&opts.watchTimeout, "timeout", 5*time.Minute, "Timeout for watching deployment readiness")
+	cmd.Flags().BoolVar(&opts.listPods, "pods", false, "With --list, show the running pods grouped by image digest and ReplicaSet, and flag mixed versions, spec/status mismatches and moved tags")
+	cmd.Flags().BoolVar(&opts.checkDrift, "check-drift", false, "With --list, add a DRIFT column showing running pods whose digest differs from what their tag resolves to now in the registry")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "Output format for --list and update results: table, wide, json, yaml, jsonpath=TEMPLATE or go-template=TEMPLATE")
+	cmd.Flags().BoolVarP(&opts.watchMode, "watch", "w", false, "Watch workload and rollback if pods fail to start")
+	cmd.Flags().DurationVar(&opts.watchTimeout, "timeout", 5*time.Minute, "Timeout for watching workload readiness")
+	cmd.Flags().StringVar(&opts.rollback, "rollback", rollbackPrompt, "Rollback policy when --watch detects a failure: auto, prompt or never (prompt rolls back automatically without a terminal)")
+	cmd.Flags().StringVar(&opts.failureRulesFile, "failure-rules", "", "YAML file with failure detection rules for --watch (maxRestarts, fatalReasons, notReadyTimeout)")
+	cmd.Flags().Int32Var(&opts.maxRestarts, "max-restarts", 3, "Restarts per container allowed since the update before --watch rolls back (-1 to disable)")
+	cmd.Flags().StringSliceVar(&opts.fatalReasons, "fatal-reasons", nil, "Additional container waiting or termination reasons that make --watch roll back")
+	cmd.Flags().DurationVar(&opts.notReadyTimeout, "not-ready-timeout", 2*time.Minute, "How long a running container may stay unready before --watch rolls back (0 to disable)")
 	cmd.Flags().BoolVar(&opts.version, "version", false, "Show version information")
-
-	// Add kubectl configuration flags
-	opts.configFlags.AddFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&opts.serverSide, "server-side", false, "Update images with server-side apply and report conflicts with other field managers")
+	cmd.Flags().BoolVar(&opts.forceConflicts, "force-conflicts", false, "Take ownership of conflicting fields when using --server-side")
+	cmd.Flags().StringVar(&opts.fieldManager, "field-manager", k8s.DefaultFieldManager, "Name of the manager used to track field ownership")
+	cmd.Flags().BoolVar(&opts.skipVerify, "skip-verify", false, "Skip checking that the new images exist and can be pulled, e.g. for air-gapped registries")
+	cmd.Flags().StringVar(&opts.platformCheck, "platform-check", platformCheckWarn, "What to do when the new images aren't built for the OS/architecture of the nodes the workload can run on: warn, block or off")
+	cmd.Flags().StringSliceVar(&opts.harborHosts, "harbor-hosts", listFromEnv("SETIMG_HARBOR_HOSTS"), "Registry hosts served by Harbor, whose tags are listed with scan, signature, label and retention details (default from $SETIMG_HARBOR_HOSTS)")
+	cmd.Flags().BoolVar(&opts.pinDigest, "pin-digest", false, "Resolve image tags through the registry and write them as repo:tag@sha256:...")
+	cmd.Flags().StringSliceVar(&opts.requireDigestNamespaces, "require-digest-namespaces", listFromEnv("SETIMG_REQUIRE_DIGEST_NAMESPACES"), "Namespaces (glob patterns) where images are always pinned to digests, as with --pin-digest (default from $SETIMG_REQUIRE_DIGEST_NAMESPACES)")
+	cmd.Flags().StringVar(&opts.dryRun, "dry-run", dryRunNone, "Only show the update: none, client (local) or server (sent with dryRun=All, including admission changes)")
+	cmd.Flags().BoolVar(&opts.diff, "diff", false, "Show a diff of the pod template, from a server-side dry run, before updating")
+	cmd.Flags().StringVar(&opts.reason, "reason", "", "Reason for the change, recorded in the change-cause and audit annotations of the workload")
+	cmd.Flags().StringSliceVarP(&opts.filenames.Filenames, "filename", "f", nil, "Filename, directory, or URL to files identifying the resources to update")
+	cmd.Flags().BoolVarP(&opts.filenames.Recursive, "recursive", "R", false, "Process the directory used in -f, --filename recursively")
+	cmd.Flags().StringVarP(&opts.filenames.Kustomize, "kustomize", "k", "", "Process the kustomization directory")
+
+	// Add kubectl configuration flags, shared with subcommands
+	opts.configFlags.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(NewRollbackCommand(opts.configFlags))
+	cmd.AddCommand(NewHistoryCommand(opts.configFlags))
 
 	return cmd
 }
@@ -359,6 +869,10 @@ You can use this command in multiple ways:
 func Execute() {
 	if err := NewRootCommand().Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
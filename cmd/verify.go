@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// verifyImages asks the registry whether every new image exists and can be pulled with the
+// local credentials, so a typo fails here instead of as ImagePullBackOff after the rollout started
+func (o *SetImageOptions) verifyImages() error {
+	if o.skipVerify {
+		return nil
+	}
+
+	for _, name := range sortedKeys(o.images) {
+		image := o.images[name]
+		fmt.Fprintf(o.log, "🔎 Verifying %s...\n", image)
+		if err := o.registry.VerifyImage(image); err != nil {
+			return fmt.Errorf("pre-flight check of container %s failed: %v (use --skip-verify for registries this machine can't reach)", name, err)
+		}
+	}
+	return nil
+}
@@ -15,9 +15,20 @@ var (
 
 // GetVersionInfo returns version information
 func GetVersionInfo() string {
-	var version string
 	var commit string
 
+	// Add commit info if available
+	if GitCommit != "unknown" && GitCommit != "" && len(GitCommit) > 7 {
+		commit = fmt.Sprintf(" (commit: %s)", GitCommit[:7])
+	}
+
+	return fmt.Sprintf("kubectl-setimg version %s%s\nGo version: %s", getVersion(), commit, runtime.Version())
+}
+
+// getVersion returns the version of this build
+func getVersion() string {
+	var version string
+
 	// Use goreleaser build-time variables if available
 	if GitTag != "unknown" && GitTag != "" {
 		version = GitTag
@@ -50,10 +61,5 @@ func GetVersionInfo() string {
 		}
 	}
 
-	// Add commit info if available
-	if GitCommit != "unknown" && GitCommit != "" && len(GitCommit) > 7 {
-		commit = fmt.Sprintf(" (commit: %s)", GitCommit[:7])
-	}
-
-	return fmt.Sprintf("kubectl-setimg version %s%s\nGo version: %s", version, commit, runtime.Version())
+	return version
 }
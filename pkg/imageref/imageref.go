@@ -0,0 +1,149 @@
+// Package imageref parses container image references, based on go-containerregistry's name package
+package imageref
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// DockerHub is the registry host of Docker Hub, as references without a registry are normalized
+const DockerHub = name.DefaultRegistry
+
+// Reference is a parsed image reference. It can carry a tag, a digest or both
+// ("repo:tag@sha256:..."), in which case the runtime pulls by digest.
+type Reference struct {
+	// Registry is the registry host with its port, e.g. "index.docker.io" or "localhost:5000"
+	Registry string
+
+	// Repository is the path in the registry, including the implicit "library/" of Docker Hub
+	Repository string
+
+	// Tag is the explicit tag, "" when the reference has none
+	Tag string
+
+	// Digest is the pinned digest such as "sha256:...", "" when the reference has none
+	Digest string
+
+	// name is the repository as written, so derived references keep short forms like "nginx"
+	name     string
+	original string
+}
+
+// Parse parses an image reference such as "nginx", "localhost:5000/app:v1", "app@sha256:..."
+// or "gcr.io/project/app:v1@sha256:..."
+func Parse(image string) (Reference, error) {
+	base, _, pinned := strings.Cut(image, "@")
+
+	var digest string
+	if pinned {
+		d, err := name.NewDigest(image)
+		if err != nil {
+			return Reference{}, fmt.Errorf("invalid image reference %q: %v", image, err)
+		}
+		digest = d.DigestStr()
+	}
+
+	// Without a default tag, an empty TagStr means the reference has no explicit tag
+	tag, err := name.NewTag(base, name.WithDefaultTag(""))
+	if err != nil {
+		return Reference{}, fmt.Errorf("invalid image reference %q: %v", image, err)
+	}
+
+	return Reference{
+		Registry:   tag.RegistryStr(),
+		Repository: tag.RepositoryStr(),
+		Tag:        tag.TagStr(),
+		Digest:     digest,
+		name:       strings.TrimSuffix(base, ":"+tag.TagStr()),
+		original:   image,
+	}, nil
+}
+
+// String returns the reference as it was parsed
+func (r Reference) String() string {
+	return r.original
+}
+
+// Name returns the repository as written, without tag or digest, e.g. "nginx" or "localhost:5000/app"
+func (r Reference) Name() string {
+	return r.name
+}
+
+// FullName returns the fully qualified repository, e.g. "index.docker.io/library/nginx"
+func (r Reference) FullName() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// WithTag returns the repository as written with another tag and no digest
+func (r Reference) WithTag(tag string) string {
+	return r.name + ":" + tag
+}
+
+// Tagged returns the tag the reference follows without its digest, "latest" when none is given,
+// or "" for a reference pinned to a digest only, which can't move
+func (r Reference) Tagged() string {
+	switch {
+	case r.Tag != "":
+		return r.WithTag(r.Tag)
+	case r.Digest != "":
+		return ""
+	}
+	return r.WithTag(name.DefaultTag)
+}
+
+// WithDigest returns the reference pinned to a digest, keeping its tag for readability
+func (r Reference) WithDigest(digest string) string {
+	if r.Tag == "" {
+		return r.name + "@" + digest
+	}
+	return r.WithTag(r.Tag) + "@" + digest
+}
+
+// IsDockerHub reports whether the reference points to Docker Hub
+func (r Reference) IsDockerHub() bool {
+	return r.Registry == DockerHub
+}
+
+// Context returns the repository for go-containerregistry calls
+func (r Reference) Context() name.Repository {
+	// Registry and repository come from a successful parse, so this can't fail
+	repo, _ := name.NewRepository(r.FullName())
+	return repo
+}
+
+// Remote returns the reference for go-containerregistry calls: the digest when pinned,
+// otherwise the tag, defaulting to "latest"
+func (r Reference) Remote() name.Reference {
+	if r.Digest != "" {
+		return r.Context().Digest(r.Digest)
+	}
+	if r.Tag != "" {
+		return r.Context().Tag(r.Tag)
+	}
+	return r.Context().Tag(name.DefaultTag)
+}
+
+// SameImage reports whether two references name the same image, ignoring registry defaults such
+// as docker.io/library. Tags and digests are only compared when both references have one.
+func (r Reference) SameImage(other Reference) bool {
+	if r.FullName() != other.FullName() {
+		return false
+	}
+	if r.Digest != "" && other.Digest != "" {
+		return r.Digest == other.Digest
+	}
+	if r.Tag != "" && other.Tag != "" {
+		return r.Tag == other.Tag
+	}
+	return true
+}
+
+// ShortDigest shortens a digest to 12 hex characters, like docker
+func ShortDigest(digest string) string {
+	if algorithm, hex, ok := strings.Cut(digest, ":"); ok && len(hex) > 12 {
+		return algorithm + ":" + hex[:12]
+	}
+	return digest
+}
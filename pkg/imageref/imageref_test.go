@@ -0,0 +1,129 @@
+package imageref
+
+import "testing"
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		image      string
+		registry   string
+		repository string
+		tag        string
+		digest     string
+		name       string
+		tagged     string
+	}{
+		{"nginx", DockerHub, "library/nginx", "", "", "nginx", "nginx:latest"},
+		{"nginx:1.27", DockerHub, "library/nginx", "1.27", "", "nginx", "nginx:1.27"},
+		{"docker.io/library/nginx", DockerHub, "library/nginx", "", "", "docker.io/library/nginx", "docker.io/library/nginx:latest"},
+		{"team/app:v1", DockerHub, "team/app", "v1", "", "team/app", "team/app:v1"},
+		{"localhost:5000/app:v1", "localhost:5000", "app", "v1", "", "localhost:5000/app", "localhost:5000/app:v1"},
+		{"localhost:5000/app", "localhost:5000", "app", "", "", "localhost:5000/app", "localhost:5000/app:latest"},
+		{"app@" + digest, DockerHub, "library/app", "", digest, "app", ""},
+		{"gcr.io/p/app:v1@" + digest, "gcr.io", "p/app", "v1", digest, "gcr.io/p/app", "gcr.io/p/app:v1"},
+		{"europe-docker.pkg.dev/p/repo/team/app:v2", "europe-docker.pkg.dev", "p/repo/team/app", "v2", "", "europe-docker.pkg.dev/p/repo/team/app", "europe-docker.pkg.dev/p/repo/team/app:v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := Parse(tt.image)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.image, err)
+			}
+			if ref.Registry != tt.registry || ref.Repository != tt.repository || ref.Tag != tt.tag || ref.Digest != tt.digest {
+				t.Errorf("Parse(%q) = %s, %s, %q, %q; want %s, %s, %q, %q", tt.image,
+					ref.Registry, ref.Repository, ref.Tag, ref.Digest, tt.registry, tt.repository, tt.tag, tt.digest)
+			}
+			if got := ref.Name(); got != tt.name {
+				t.Errorf("Name() = %q, want %q", got, tt.name)
+			}
+			if got := ref.Tagged(); got != tt.tagged {
+				t.Errorf("Tagged() = %q, want %q", got, tt.tagged)
+			}
+			if got := ref.String(); got != tt.image {
+				t.Errorf("String() = %q, want %q", got, tt.image)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, image := range []string{"", "nginx:", "Nginx", "registry.example.com/Team/app:v1", "app@sha256:short", "app:bad tag"} {
+		if _, err := Parse(image); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", image)
+		}
+	}
+}
+
+func TestWithTagAndDigest(t *testing.T) {
+	tests := []struct {
+		image      string
+		withTag    string
+		withDigest string
+	}{
+		{"nginx", "nginx:v2", "nginx@" + digest},
+		{"nginx:1.27", "nginx:v2", "nginx:1.27@" + digest},
+		{"localhost:5000/app:v1", "localhost:5000/app:v2", "localhost:5000/app:v1@" + digest},
+		{"gcr.io/p/app:v1@" + digest, "gcr.io/p/app:v2", "gcr.io/p/app:v1@" + digest},
+	}
+
+	for _, tt := range tests {
+		ref, err := Parse(tt.image)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.image, err)
+		}
+		if got := ref.WithTag("v2"); got != tt.withTag {
+			t.Errorf("%q.WithTag(v2) = %q, want %q", tt.image, got, tt.withTag)
+		}
+		if got := ref.WithDigest(digest); got != tt.withDigest {
+			t.Errorf("%q.WithDigest() = %q, want %q", tt.image, got, tt.withDigest)
+		}
+	}
+}
+
+func TestSameImage(t *testing.T) {
+	other := "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"nginx", "docker.io/library/nginx:latest", true},
+		{"nginx:1.27", "index.docker.io/library/nginx:1.27", true},
+		{"nginx:1.27", "nginx:1.26", false},
+		{"nginx:1.27", "nginx@" + digest, true},
+		{"nginx@" + digest, "nginx:1.27@" + other, false},
+		{"nginx@" + digest, "nginx:1.26@" + digest, true},
+		{"nginx", "localhost:5000/nginx", false},
+		{"team/app:v1", "library/app:v1", false},
+	}
+
+	for _, tt := range tests {
+		a, err := Parse(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Parse(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.SameImage(b); got != tt.want {
+			t.Errorf("SameImage(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestShortDigest(t *testing.T) {
+	tests := map[string]string{
+		digest:                "sha256:0123456789ab",
+		"sha256:0123456789ab": "sha256:0123456789ab",
+		"sha256:short":        "sha256:short",
+		"":                    "",
+		"not-a-digest":        "not-a-digest",
+	}
+	for input, want := range tests {
+		if got := ShortDigest(input); got != want {
+			t.Errorf("ShortDigest(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
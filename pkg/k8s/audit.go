@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations recorded on a workload by every image update. The deployment controller copies
+// them to the new ReplicaSet, so each revision keeps the record of the change that created it.
+const (
+	annotationPrefix         = "setimg.tkuchiki.github.io/"
+	previousImagesAnnotation = annotationPrefix + "previous-images"
+	imagesAnnotation         = annotationPrefix + "images"
+	digestsAnnotation        = annotationPrefix + "digests"
+	userAnnotation           = annotationPrefix + "user"
+	versionAnnotation        = annotationPrefix + "version"
+	reasonAnnotation         = annotationPrefix + "reason"
+	timestampAnnotation      = annotationPrefix + "timestamp"
+)
+
+// ChangeInfo describes who makes image updates and why
+type ChangeInfo struct {
+	User    string
+	Version string
+	Reason  string
+
+	// Digests maps container names to the manifest digests their new images resolved to
+	Digests map[string]string
+}
+
+// SetChangeInfo sets the change details recorded with subsequent image updates
+func (c *Client) SetChangeInfo(info ChangeInfo) {
+	c.changeInfo = info
+}
+
+// CurrentUser returns the user the API server authenticates the client as
+func (c *Client) CurrentUser() (string, error) {
+	review, err := c.clientset.AuthenticationV1().SelfSubjectReviews().Create(context.Background(),
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to review current user: %v", err)
+	}
+	return review.Status.UserInfo.Username, nil
+}
+
+// changeAnnotations returns the annotations recording an update of a pod spec to the given images
+func changeAnnotations(info ChangeInfo, spec corev1.PodSpec, images map[string]string) map[string]string {
+	previous := make(map[string]string, len(images))
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		if _, ok := images[container.Name]; ok {
+			previous[container.Name] = container.Image
+		}
+	}
+
+	changeCause := "setimg " + strings.ReplaceAll(formatImages(images), ",", " ")
+	if info.Reason != "" {
+		changeCause += ": " + info.Reason
+	}
+
+	digests := make(map[string]string, len(info.Digests))
+	for name, digest := range info.Digests {
+		if _, ok := images[name]; ok {
+			digests[name] = digest
+		}
+	}
+
+	return map[string]string{
+		changeCauseAnnotation:    changeCause,
+		previousImagesAnnotation: formatImages(previous),
+		imagesAnnotation:         formatImages(images),
+		digestsAnnotation:        formatImages(digests),
+		userAnnotation:           info.User,
+		versionAnnotation:        info.Version,
+		reasonAnnotation:         info.Reason,
+		timestampAnnotation:      time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// formatImages encodes container images as "name=image,..." sorted by container name
+func formatImages(images map[string]string) string {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + images[name]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseImages decodes container images written by formatImages
+func parseImages(value string) map[string]string {
+	images := make(map[string]string)
+	if value == "" {
+		return images
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, image, ok := strings.Cut(pair, "=")
+		if ok {
+			images[name] = image
+		}
+	}
+	return images
+}
@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"sort"
+	"time"
+)
+
+// ImageChange is a change of one container image in a deployment revision
+type ImageChange struct {
+	Revision      int64     `json:"revision"`
+	Time          time.Time `json:"time"`
+	Container     string    `json:"container"`
+	PreviousImage string    `json:"previousImage,omitempty"`
+	Image         string    `json:"image"`
+	Digest        string    `json:"digest,omitempty"`
+	User          string    `json:"user,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// GetImageHistory returns the image changes of a deployment, oldest first.
+// Changes are found by comparing ReplicaSet revisions, and are attributed with the
+// annotations setimg recorded when the change was made by setimg.
+func (c *Client) GetImageHistory(w Workload) ([]ImageChange, error) {
+	revisions, err := c.ListRevisions(w)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+
+	var changes []ImageChange
+	var previous map[string]string
+	for _, revision := range revisions {
+		images := make(map[string]string, len(revision.Containers))
+		for _, container := range revision.Containers {
+			images[container.Name] = container.Image
+		}
+
+		record, recorded := changeRecordOf(revision, images)
+		for _, container := range revision.Containers {
+			change := ImageChange{
+				Revision:      revision.Number,
+				Time:          revision.CreatedAt,
+				Container:     container.Name,
+				PreviousImage: previous[container.Name],
+				Image:         container.Image,
+			}
+			if recorded {
+				if _, ok := record.images[container.Name]; ok {
+					// The record knows the previous image even when older ReplicaSets were pruned
+					change.PreviousImage = record.previousImages[container.Name]
+					change.Digest = record.digests[container.Name]
+					change.User = record.User
+					change.Version = record.Version
+					change.Reason = record.Reason
+				}
+			}
+
+			if change.PreviousImage == change.Image {
+				continue
+			}
+			changes = append(changes, change)
+		}
+		previous = images
+	}
+
+	return changes, nil
+}
+
+// changeRecord is an image update recorded in annotations by setimg
+type changeRecord struct {
+	ChangeInfo
+	previousImages map[string]string
+	images         map[string]string
+	digests        map[string]string
+}
+
+// changeRecordOf returns the setimg record of a revision. Annotations stay on the deployment
+// after the update and are copied to later ReplicaSets too, so a record only belongs to the
+// revision if the images it names are the revision's images.
+func changeRecordOf(revision Revision, images map[string]string) (changeRecord, bool) {
+	value, ok := revision.annotations[imagesAnnotation]
+	if !ok {
+		return changeRecord{}, false
+	}
+
+	record := changeRecord{
+		ChangeInfo: ChangeInfo{
+			User:    revision.annotations[userAnnotation],
+			Version: revision.annotations[versionAnnotation],
+			Reason:  revision.annotations[reasonAnnotation],
+		},
+		previousImages: parseImages(revision.annotations[previousImagesAnnotation]),
+		images:         parseImages(value),
+		digests:        parseImages(revision.annotations[digestsAnnotation]),
+	}
+	for name, image := range record.images {
+		if images[name] != image {
+			return changeRecord{}, false
+		}
+	}
+	return record, true
+}
@@ -2,26 +2,54 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"sort"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
 )
 
+// revisionAnnotation holds the rollout revision of Deployments and their ReplicaSets
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// ErrPaused is returned when watching a paused Deployment, which never rolls out
+var ErrPaused = errors.New("deployment is paused; the new pod template rolls out once it is resumed (kubectl rollout resume)")
+
+// ErrOnDelete is returned when watching a StatefulSet or DaemonSet with the OnDelete update
+// strategy, whose pods only get the new pod template when they are deleted
+var ErrOnDelete = errors.New("update strategy is OnDelete; pods get the new pod template once they are deleted, so there is no rollout to watch")
+
 // Client wraps Kubernetes operations
 type Client struct {
-	clientset kubernetes.Interface
-	namespace string
+	clientset        kubernetes.Interface
+	restClientGetter genericclioptions.RESTClientGetter
+	namespace        string
+	enforceNamespace bool
+	updateOptions    UpdateOptions
+	failureRules     *FailureRules
+	changeInfo       ChangeInfo
 }
 
+// ContainerType distinguishes regular, init and ephemeral containers
+type ContainerType string
+
+const (
+	ContainerTypeRegular   ContainerType = "container"
+	ContainerTypeInit      ContainerType = "init"
+	ContainerTypeEphemeral ContainerType = "ephemeral"
+)
+
 // ContainerInfo represents container information
 type ContainerInfo struct {
 	Name  string
 	Image string
 	Index int
+	Type  ContainerType
+	Pod   string // Set for ephemeral containers only
 }
 
 // NewClient creates a new Kubernetes client
@@ -36,14 +64,16 @@ func NewClient(configFlags *genericclioptions.ConfigFlags) (*Client, error) {
 		return nil, err
 	}
 
-	namespace, _, err := configFlags.ToRawKubeConfigLoader().Namespace()
+	namespace, enforceNamespace, err := configFlags.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		clientset: clientset,
-		namespace: namespace,
+		clientset:        clientset,
+		restClientGetter: configFlags,
+		namespace:        namespace,
+		enforceNamespace: enforceNamespace,
 	}, nil
 }
 
@@ -57,147 +87,214 @@ func (c *Client) GetNamespace() string {
 	return c.namespace
 }
 
-// GetContainers returns containers in a deployment
-func (c *Client) GetContainers(deploymentName string) ([]ContainerInfo, error) {
+// GetContainers returns containers and init containers in a workload's pod template
+func (c *Client) GetContainers(w Workload) ([]ContainerInfo, error) {
 	ctx := context.Background()
 
-	deployment, err := c.clientset.AppsV1().Deployments(c.namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	template, _, err := c.getPodTemplate(ctx, w)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get deployment %s: %v", deploymentName, err)
+		return nil, err
 	}
 
 	var containers []ContainerInfo
-	for i, container := range deployment.Spec.Template.Spec.Containers {
+	for i, container := range template.Spec.InitContainers {
 		containers = append(containers, ContainerInfo{
 			Name:  container.Name,
 			Image: container.Image,
 			Index: i,
+			Type:  ContainerTypeInit,
 		})
 	}
+	for i, container := range template.Spec.Containers {
+		containers = append(containers, ContainerInfo{
+			Name:  container.Name,
+			Image: container.Image,
+			Index: i,
+			Type:  ContainerTypeRegular,
+		})
+	}
+
+	return containers, nil
+}
+
+// GetEphemeralContainers returns ephemeral containers attached to the running pods of a workload.
+// They are not part of the pod template and cannot be updated.
+func (c *Client) GetEphemeralContainers(w Workload) ([]ContainerInfo, error) {
+	ctx := context.Background()
+
+	pods, err := c.selectPods(ctx, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s: %v", w, err)
+	}
+
+	var containers []ContainerInfo
+	for _, pod := range pods {
+		for i, container := range pod.Spec.EphemeralContainers {
+			containers = append(containers, ContainerInfo{
+				Name:  container.Name,
+				Image: container.Image,
+				Index: i,
+				Type:  ContainerTypeEphemeral,
+				Pod:   pod.Name,
+			})
+		}
+	}
 
 	return containers, nil
 }
 
-// GetCurrentImage returns the current image for a container in a deployment
-func (c *Client) GetCurrentImage(deploymentName, containerName string) (string, error) {
+// PodImage is the image a container of a running pod was started from
+type PodImage struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	ImageID   string `json:"imageID,omitempty"`
+	SpecImage string `json:"specImage"`
+	Ready     bool   `json:"ready"`
+
+	// Revision is the ReplicaSet of the pod, or its controller revision for StatefulSets and DaemonSets
+	Revision string `json:"revision,omitempty"`
+}
+
+// GetPodImages returns the images the containers of a workload's pods are running
+func (c *Client) GetPodImages(w Workload) ([]PodImage, error) {
 	ctx := context.Background()
 
-	deployment, err := c.clientset.AppsV1().Deployments(c.namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	pods, err := c.selectPods(ctx, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s: %v", w, err)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	var images []PodImage
+	for _, pod := range pods {
+		specImages := make(map[string]string)
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			specImages[container.Name] = container.Image
+		}
+
+		revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "ReplicaSet" {
+			revision = owner.Name
+		}
+
+		for _, status := range containerStatuses(pod) {
+			images = append(images, PodImage{
+				Pod:       pod.Name,
+				Container: status.Name,
+				Image:     status.Image,
+				ImageID:   status.ImageID,
+				SpecImage: specImages[status.Name],
+				Ready:     status.Ready,
+				Revision:  revision,
+			})
+		}
+	}
+
+	return images, nil
+}
+
+// GetCurrentImage returns the current image for a container or init container in a workload
+func (c *Client) GetCurrentImage(w Workload, containerName string) (string, error) {
+	containers, err := c.GetContainers(w)
 	if err != nil {
-		return "", fmt.Errorf("failed to get deployment %s: %v", deploymentName, err)
+		return "", err
 	}
 
-	for _, container := range deployment.Spec.Template.Spec.Containers {
+	for _, container := range containers {
 		if container.Name == containerName {
 			return container.Image, nil
 		}
 	}
 
-	return "", fmt.Errorf("container %s not found in deployment %s", containerName, deploymentName)
+	return "", fmt.Errorf("container %s not found in %s", containerName, w)
 }
 
-// UpdateContainerImage updates a container image in a deployment using strategic merge patch
-func (c *Client) UpdateContainerImage(deploymentName, containerName, newImage string) error {
-	ctx := context.Background()
+// UpdateContainerImages updates container and init container images in a workload with a single
+// patch, so that a Deployment rolls out one new ReplicaSet for all of them
+func (c *Client) UpdateContainerImages(w Workload, images map[string]string) error {
+	_, _, err := c.updateContainerImages(w, images, false)
+	return err
+}
 
-	// Create strategic merge patch
-	patch := fmt.Sprintf(`{
-        "spec": {
-            "template": {
-                "spec": {
-                    "containers": [
-                        {
-                            "name": "%s",
-                            "image": "%s"
-                        }
-                    ]
-                }
-            }
-        }
-    }`, containerName, newImage)
-
-	_, err := c.clientset.AppsV1().Deployments(c.namespace).Patch(
-		ctx,
-		deploymentName,
-		types.StrategicMergePatchType,
-		[]byte(patch),
-		metav1.PatchOptions{},
-	)
+// PreviewContainerImages returns the pod template of a workload before and after an image update.
+// With serverDryRun the update is sent as a server-side dry run, so the result includes the
+// defaults and changes of mutating admission; otherwise the images are only replaced locally.
+func (c *Client) PreviewContainerImages(w Workload, images map[string]string, serverDryRun bool) (before, after *corev1.PodTemplateSpec, err error) {
+	if serverDryRun {
+		return c.updateContainerImages(w, images, true)
+	}
 
+	template, _, err := c.getPodTemplate(context.Background(), w)
 	if err != nil {
-		return fmt.Errorf("failed to patch deployment: %v", err)
+		return nil, nil, err
 	}
 
-	return nil
+	after = template.DeepCopy()
+	for name, image := range images {
+		if !setImage(&after.Spec, name, image) {
+			return nil, nil, fmt.Errorf("container %s not found in %s", name, w)
+		}
+	}
+	return template, after, nil
 }
 
-// CheckDeploymentReadiness checks if a deployment is ready
-func (c *Client) CheckDeploymentReadiness(deploymentName string) (bool, error) {
+// updateContainerImages patches the images and returns the pod template before and after the patch
+func (c *Client) updateContainerImages(w Workload, images map[string]string, dryRun bool) (before, after *corev1.PodTemplateSpec, err error) {
 	ctx := context.Background()
 
-	deployment, err := c.clientset.AppsV1().Deployments(c.namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if len(images) == 0 {
+		return nil, nil, fmt.Errorf("no container images to update")
+	}
+
+	template, _, err := c.getPodTemplate(ctx, w)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
 
-	// Check if all replicas are available
-	if deployment.Status.ReadyReplicas == *deployment.Spec.Replicas &&
-		deployment.Status.UpdatedReplicas == *deployment.Spec.Replicas {
-		return true, nil
+	templatePatch, err := buildTemplatePatch(template.Spec, images)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v in %s", err, w)
 	}
 
-	// Check for failed pods
-	labelSelector := fmt.Sprintf("app=%s", deploymentName)
-	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
+	result, err := c.applyTemplatePatch(ctx, w, templatePatch, changeAnnotations(c.changeInfo, template.Spec, images), dryRun)
 	if err != nil {
-		return false, err
+		return nil, nil, fmt.Errorf("failed to patch %s: %v", w, err)
 	}
 
-	for _, pod := range pods.Items {
-		if pod.Status.Phase == "Failed" {
-			return false, fmt.Errorf("pod %s failed", pod.Name)
-		}
+	return template, result, nil
+}
 
-		// Check for CrashLoopBackOff, ImagePullError, etc.
-		for _, containerStatus := range pod.Status.ContainerStatuses {
-			if containerStatus.RestartCount > 3 {
-				return false, fmt.Errorf("container %s in pod %s is restarting too frequently", containerStatus.Name, pod.Name)
-			}
-
-			if containerStatus.State.Waiting != nil {
-				reason := containerStatus.State.Waiting.Reason
-				if reason == "ImagePullBackOff" || reason == "CrashLoopBackOff" || reason == "ErrImagePull" {
-					return false, fmt.Errorf("container %s in pod %s has problem: %s", containerStatus.Name, pod.Name, reason)
-				}
-			}
+// setImage sets the image of a container or init container in a pod spec
+func setImage(spec *corev1.PodSpec, name, image string) bool {
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].Name == name {
+			spec.InitContainers[i].Image = image
+			return true
 		}
 	}
-
-	return false, nil
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			spec.Containers[i].Image = image
+			return true
+		}
+	}
+	return false
 }
 
-// WatchDeployment monitors deployment readiness with timeout
-func (c *Client) WatchDeployment(deploymentName string, timeout time.Duration) error {
-	timeoutCh := time.After(timeout)
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-timeoutCh:
-			return fmt.Errorf("timeout: deployment didn't become ready within %v", timeout)
-
-		case <-ticker.C:
-			ready, err := c.CheckDeploymentReadiness(deploymentName)
-			if err != nil {
-				return err
-			}
-
-			if ready {
-				return nil
-			}
+// hasContainer reports whether a pod spec has a container or init container with the given name
+func hasContainer(spec corev1.PodSpec, name string) bool {
+	for _, container := range spec.InitContainers {
+		if container.Name == name {
+			return true
+		}
+	}
+	for _, container := range spec.Containers {
+		if container.Name == name {
+			return true
 		}
 	}
+	return false
 }
@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// NodePlatform is an OS/architecture combination of nodes a workload can be scheduled on
+type NodePlatform struct {
+	OS           string
+	Architecture string
+	Nodes        []string
+}
+
+// String returns the platform in "os/arch" form
+func (p NodePlatform) String() string {
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// SchedulablePlatforms returns the platforms of the nodes a workload's pods can be scheduled on,
+// honoring its nodeSelector, required node affinity and tolerations. Cordoned nodes are skipped,
+// except for DaemonSets, whose pods run on cordoned nodes too.
+func (c *Client) SchedulablePlatforms(w Workload) ([]NodePlatform, error) {
+	ctx := context.Background()
+
+	template, _, err := c.getPodTemplate(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	spec := template.Spec.DeepCopy()
+	daemonSet := w.Kind == KindDaemonSet
+	if daemonSet {
+		spec.Tolerations = append(spec.Tolerations, daemonSetTolerations(spec)...)
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	platforms := make(map[string]*NodePlatform)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if (node.Spec.Unschedulable && !daemonSet) || !schedulable(spec, node) {
+			continue
+		}
+
+		info := node.Status.NodeInfo
+		key := info.OperatingSystem + "/" + info.Architecture
+		platform, ok := platforms[key]
+		if !ok {
+			platform = &NodePlatform{OS: info.OperatingSystem, Architecture: info.Architecture}
+			platforms[key] = platform
+		}
+		platform.Nodes = append(platform.Nodes, node.Name)
+	}
+
+	var result []NodePlatform
+	for _, platform := range platforms {
+		result = append(result, *platform)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result, nil
+}
+
+// daemonSetTolerations returns the tolerations the DaemonSet controller adds to its pods, so they
+// run on nodes that are cordoned, under resource pressure, not ready or unreachable
+func daemonSetTolerations(spec *corev1.PodSpec) []corev1.Toleration {
+	toleration := func(key string, effect corev1.TaintEffect) corev1.Toleration {
+		return corev1.Toleration{Key: key, Operator: corev1.TolerationOpExists, Effect: effect}
+	}
+	tolerations := []corev1.Toleration{
+		toleration(corev1.TaintNodeNotReady, corev1.TaintEffectNoExecute),
+		toleration(corev1.TaintNodeUnreachable, corev1.TaintEffectNoExecute),
+		toleration(corev1.TaintNodeDiskPressure, corev1.TaintEffectNoSchedule),
+		toleration(corev1.TaintNodeMemoryPressure, corev1.TaintEffectNoSchedule),
+		toleration(corev1.TaintNodePIDPressure, corev1.TaintEffectNoSchedule),
+		toleration(corev1.TaintNodeUnschedulable, corev1.TaintEffectNoSchedule),
+	}
+	if spec.HostNetwork {
+		tolerations = append(tolerations, toleration(corev1.TaintNodeNetworkUnavailable, corev1.TaintEffectNoSchedule))
+	}
+	return tolerations
+}
+
+// schedulable reports whether a pod spec fits a node by nodeSelector, required node affinity
+// and NoSchedule/NoExecute taints. Resources are not considered.
+func schedulable(spec *corev1.PodSpec, node *corev1.Node) bool {
+	if !labels.SelectorFromSet(spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+
+	if affinity := spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			// Terms are ORed, the requirements of a term ANDed
+			matched := false
+			for _, term := range required.NodeSelectorTerms {
+				if nodeSelectorTermMatches(term, node) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorTermMatches reports whether a node matches all requirements of a node selector term
+func nodeSelectorTermMatches(term corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	// An empty term matches no nodes, like the scheduler
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+
+	for _, requirement := range term.MatchExpressions {
+		if !nodeSelectorRequirementMatches(requirement, labels.Set(node.Labels)) {
+			return false
+		}
+	}
+	// metadata.name is the only supported field
+	for _, requirement := range term.MatchFields {
+		if requirement.Key != metav1.ObjectNameField ||
+			!nodeSelectorRequirementMatches(requirement, labels.Set{metav1.ObjectNameField: node.Name}) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorRequirementMatches evaluates a node selector requirement against a label set
+func nodeSelectorRequirementMatches(requirement corev1.NodeSelectorRequirement, set labels.Set) bool {
+	var op selection.Operator
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn:
+		op = selection.In
+	case corev1.NodeSelectorOpNotIn:
+		op = selection.NotIn
+	case corev1.NodeSelectorOpExists:
+		op = selection.Exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		op = selection.DoesNotExist
+	case corev1.NodeSelectorOpGt:
+		op = selection.GreaterThan
+	case corev1.NodeSelectorOpLt:
+		op = selection.LessThan
+	default:
+		return false
+	}
+
+	r, err := labels.NewRequirement(requirement.Key, op, requirement.Values)
+	if err != nil {
+		return false
+	}
+	return r.Matches(set)
+}
@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testNode returns a node of the given architecture with a pool label
+func testNode(name, arch, pool string, unschedulable bool, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			corev1.LabelArchStable: arch,
+			"pool":                 pool,
+		}},
+		Spec: corev1.NodeSpec{Unschedulable: unschedulable, Taints: taints},
+		Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+			OperatingSystem: "linux",
+			Architecture:    arch,
+		}},
+	}
+}
+
+// platformNodes formats platforms as "linux/amd64=node-a,node-b;..." with sorted node names
+func platformNodes(platforms []NodePlatform) string {
+	var parts []string
+	for _, platform := range platforms {
+		slices.Sort(platform.Nodes)
+		parts = append(parts, platform.String()+"="+strings.Join(platform.Nodes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+func TestSchedulablePlatforms(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	cordonTaint := corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}
+	pressureTaint := corev1.Taint{Key: corev1.TaintNodeDiskPressure, Effect: corev1.TaintEffectNoSchedule}
+	nodes := []runtime.Object{
+		testNode("amd-1", "amd64", "general", false),
+		testNode("arm-1", "arm64", "general", false),
+		testNode("arm-2", "arm64", "batch", false),
+		testNode("gpu-1", "amd64", "gpu", false, gpuTaint),
+		testNode("cordoned-1", "amd64", "general", true, cordonTaint),
+		testNode("full-1", "arm64", "general", false, pressureTaint),
+	}
+
+	tests := []struct {
+		name string
+		kind WorkloadKind
+		spec corev1.PodSpec
+		want string
+	}{
+		{
+			name: "no constraints",
+			kind: KindDeployment,
+			want: "linux/amd64=amd-1;linux/arm64=arm-1,arm-2",
+		},
+		{
+			name: "nodeSelector",
+			kind: KindDeployment,
+			spec: corev1.PodSpec{NodeSelector: map[string]string{"pool": "batch"}},
+			want: "linux/arm64=arm-2",
+		},
+		{
+			name: "required node affinity",
+			kind: KindDeployment,
+			spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"},
+					}},
+				}}},
+			}}},
+			want: "linux/amd64=amd-1",
+		},
+		{
+			name: "tolerated taint",
+			kind: KindDeployment,
+			spec: corev1.PodSpec{
+				NodeSelector: map[string]string{"pool": "gpu"},
+				Tolerations:  []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			want: "linux/amd64=gpu-1",
+		},
+		{
+			name: "daemonset runs on cordoned nodes and nodes under pressure",
+			kind: KindDaemonSet,
+			want: "linux/amd64=amd-1,cordoned-1;linux/arm64=arm-1,arm-2,full-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{Spec: tt.spec}
+			var workload runtime.Object
+			switch tt.kind {
+			case KindDeployment:
+				workload = &appsv1.Deployment{ObjectMeta: testMeta(), Spec: appsv1.DeploymentSpec{Selector: testSelector, Template: template}}
+			case KindDaemonSet:
+				workload = &appsv1.DaemonSet{ObjectMeta: testMeta(), Spec: appsv1.DaemonSetSpec{Selector: testSelector, Template: template}}
+			}
+
+			client := &Client{clientset: fake.NewSimpleClientset(append(nodes, workload)...), namespace: testNamespace}
+			platforms, err := client.SchedulablePlatforms(Workload{Kind: tt.kind, Name: "web"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := platformNodes(platforms); got != tt.want {
+				t.Errorf("SchedulablePlatforms() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
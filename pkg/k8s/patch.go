@@ -0,0 +1,310 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	batchv1ac "k8s.io/client-go/applyconfigurations/batch/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// DefaultFieldManager is the field manager name recorded for changes made by setimg
+const DefaultFieldManager = "kubectl-setimg"
+
+// UpdateOptions controls how image updates are sent to the API server
+type UpdateOptions struct {
+	// ServerSide uses server-side apply instead of a strategic merge patch
+	ServerSide bool
+
+	// ForceConflicts takes ownership of fields managed by others (server-side apply only)
+	ForceConflicts bool
+
+	// FieldManager is the manager name recorded in managedFields
+	FieldManager string
+}
+
+// dryRunAll returns the API dry-run setting for an update
+func dryRunAll(enabled bool) []string {
+	if enabled {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// SetUpdateOptions sets how subsequent image updates are sent to the API server
+func (c *Client) SetUpdateOptions(opts UpdateOptions) {
+	c.updateOptions = opts
+}
+
+// buildTemplatePatch builds a pod template that only sets the images of the given containers
+func buildTemplatePatch(spec corev1.PodSpec, images map[string]string) (*corev1ac.PodTemplateSpecApplyConfiguration, error) {
+	podSpec := corev1ac.PodSpec()
+	matched := 0
+
+	for _, container := range spec.InitContainers {
+		if image, ok := images[container.Name]; ok {
+			podSpec.WithInitContainers(corev1ac.Container().WithName(container.Name).WithImage(image))
+			matched++
+		}
+	}
+	for _, container := range spec.Containers {
+		if image, ok := images[container.Name]; ok {
+			podSpec.WithContainers(corev1ac.Container().WithName(container.Name).WithImage(image))
+			matched++
+		}
+	}
+
+	// Container names are unique across containers and init containers of a pod
+	if matched != len(images) {
+		for name := range images {
+			if !hasContainer(spec, name) {
+				return nil, fmt.Errorf("container %s not found", name)
+			}
+		}
+	}
+
+	return corev1ac.PodTemplateSpec().WithSpec(podSpec), nil
+}
+
+// applyTemplatePatch sends a pod template patch and workload annotations to a workload
+// using the configured update options, and returns the resulting pod template.
+// A dry run is validated and admitted by the API server without being persisted.
+func (c *Client) applyTemplatePatch(ctx context.Context, w Workload, template *corev1ac.PodTemplateSpecApplyConfiguration, annotations map[string]string, dryRun bool) (*corev1.PodTemplateSpec, error) {
+	var result *corev1.PodTemplateSpec
+	var err error
+	if c.updateOptions.ServerSide {
+		result, err = c.serverSideApply(ctx, w, template, annotations, dryRun)
+	} else {
+		result, err = c.strategicMergePatch(ctx, w, template, annotations, dryRun)
+	}
+
+	if apierrors.IsConflict(err) {
+		return nil, describeConflict(w, err)
+	}
+	return result, err
+}
+
+// strategicMergePatch nests the template at the template path of the workload kind and patches it
+func (c *Client) strategicMergePatch(ctx context.Context, w Workload, template *corev1ac.PodTemplateSpecApplyConfiguration, annotations map[string]string, dryRun bool) (*corev1.PodTemplateSpec, error) {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{"template": template},
+	}
+	if w.Kind == KindCronJob {
+		patch = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"jobTemplate": map[string]interface{}{
+					"spec": map[string]interface{}{"template": template},
+				},
+			},
+		}
+	}
+	patch["metadata"] = map[string]interface{}{"annotations": annotations}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build patch: %v", err)
+	}
+
+	opts := metav1.PatchOptions{FieldManager: c.fieldManager(), DryRun: dryRunAll(dryRun)}
+	pt := types.StrategicMergePatchType
+	ns := c.namespaceOf(w)
+
+	var obj runtime.Object
+	switch w.Kind {
+	case KindDeployment:
+		obj, err = c.clientset.AppsV1().Deployments(ns).Patch(ctx, w.Name, pt, data, opts)
+	case KindStatefulSet:
+		obj, err = c.clientset.AppsV1().StatefulSets(ns).Patch(ctx, w.Name, pt, data, opts)
+	case KindDaemonSet:
+		obj, err = c.clientset.AppsV1().DaemonSets(ns).Patch(ctx, w.Name, pt, data, opts)
+	case KindReplicaSet:
+		obj, err = c.clientset.AppsV1().ReplicaSets(ns).Patch(ctx, w.Name, pt, data, opts)
+	case KindCronJob:
+		obj, err = c.clientset.BatchV1().CronJobs(ns).Patch(ctx, w.Name, pt, data, opts)
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", w.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return podTemplateOf(obj)
+}
+
+// serverSideApply applies the template with server-side apply, owning only the image fields
+// and the given annotations. The fields the manager already owns, such as images set for other
+// containers by earlier runs, are extracted from the object and applied again, as server-side
+// apply removes owned fields that are left out of an apply.
+func (c *Client) serverSideApply(ctx context.Context, w Workload, template *corev1ac.PodTemplateSpecApplyConfiguration, annotations map[string]string, dryRun bool) (*corev1.PodTemplateSpec, error) {
+	opts := metav1.ApplyOptions{
+		FieldManager: c.fieldManager(),
+		Force:        c.updateOptions.ForceConflicts,
+		DryRun:       dryRunAll(dryRun),
+	}
+	getOpts := metav1.GetOptions{}
+	ns := c.namespaceOf(w)
+
+	var obj runtime.Object
+	var err error
+	switch w.Kind {
+	case KindDeployment:
+		var current *appsv1.Deployment
+		if current, err = c.clientset.AppsV1().Deployments(ns).Get(ctx, w.Name, getOpts); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		ac, err := appsv1ac.ExtractDeployment(current, opts.FieldManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract the fields of %s owned by %s: %v", w, opts.FieldManager, err)
+		}
+		if ac.Spec == nil {
+			ac.WithSpec(appsv1ac.DeploymentSpec())
+		}
+		ac.WithAnnotations(annotations).Spec.WithTemplate(mergeOwnedTemplate(ac.Spec.Template, template))
+		obj, err = c.clientset.AppsV1().Deployments(ns).Apply(ctx, ac, opts)
+		if err != nil {
+			return nil, err
+		}
+	case KindStatefulSet:
+		var current *appsv1.StatefulSet
+		if current, err = c.clientset.AppsV1().StatefulSets(ns).Get(ctx, w.Name, getOpts); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		ac, err := appsv1ac.ExtractStatefulSet(current, opts.FieldManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract the fields of %s owned by %s: %v", w, opts.FieldManager, err)
+		}
+		if ac.Spec == nil {
+			ac.WithSpec(appsv1ac.StatefulSetSpec())
+		}
+		ac.WithAnnotations(annotations).Spec.WithTemplate(mergeOwnedTemplate(ac.Spec.Template, template))
+		obj, err = c.clientset.AppsV1().StatefulSets(ns).Apply(ctx, ac, opts)
+		if err != nil {
+			return nil, err
+		}
+	case KindDaemonSet:
+		var current *appsv1.DaemonSet
+		if current, err = c.clientset.AppsV1().DaemonSets(ns).Get(ctx, w.Name, getOpts); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		ac, err := appsv1ac.ExtractDaemonSet(current, opts.FieldManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract the fields of %s owned by %s: %v", w, opts.FieldManager, err)
+		}
+		if ac.Spec == nil {
+			ac.WithSpec(appsv1ac.DaemonSetSpec())
+		}
+		ac.WithAnnotations(annotations).Spec.WithTemplate(mergeOwnedTemplate(ac.Spec.Template, template))
+		obj, err = c.clientset.AppsV1().DaemonSets(ns).Apply(ctx, ac, opts)
+		if err != nil {
+			return nil, err
+		}
+	case KindReplicaSet:
+		var current *appsv1.ReplicaSet
+		if current, err = c.clientset.AppsV1().ReplicaSets(ns).Get(ctx, w.Name, getOpts); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		ac, err := appsv1ac.ExtractReplicaSet(current, opts.FieldManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract the fields of %s owned by %s: %v", w, opts.FieldManager, err)
+		}
+		if ac.Spec == nil {
+			ac.WithSpec(appsv1ac.ReplicaSetSpec())
+		}
+		ac.WithAnnotations(annotations).Spec.WithTemplate(mergeOwnedTemplate(ac.Spec.Template, template))
+		obj, err = c.clientset.AppsV1().ReplicaSets(ns).Apply(ctx, ac, opts)
+		if err != nil {
+			return nil, err
+		}
+	case KindCronJob:
+		var current *batchv1.CronJob
+		if current, err = c.clientset.BatchV1().CronJobs(ns).Get(ctx, w.Name, getOpts); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		ac, err := batchv1ac.ExtractCronJob(current, opts.FieldManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract the fields of %s owned by %s: %v", w, opts.FieldManager, err)
+		}
+		if ac.Spec == nil {
+			ac.WithSpec(batchv1ac.CronJobSpec())
+		}
+		if ac.Spec.JobTemplate == nil {
+			ac.Spec.WithJobTemplate(batchv1ac.JobTemplateSpec())
+		}
+		if ac.Spec.JobTemplate.Spec == nil {
+			ac.Spec.JobTemplate.WithSpec(batchv1ac.JobSpec())
+		}
+		ac.WithAnnotations(annotations)
+		ac.Spec.JobTemplate.Spec.WithTemplate(mergeOwnedTemplate(ac.Spec.JobTemplate.Spec.Template, template))
+		obj, err = c.clientset.BatchV1().CronJobs(ns).Apply(ctx, ac, opts)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", w.Kind)
+	}
+	return podTemplateOf(obj)
+}
+
+// mergeOwnedTemplate sets the container images of template on the pod template fields the
+// manager already owns, keeping the images it set earlier for other containers
+func mergeOwnedTemplate(owned, template *corev1ac.PodTemplateSpecApplyConfiguration) *corev1ac.PodTemplateSpecApplyConfiguration {
+	if owned == nil || owned.Spec == nil {
+		return template
+	}
+
+	owned.Spec.InitContainers = mergeContainerImages(owned.Spec.InitContainers, template.Spec.InitContainers)
+	owned.Spec.Containers = mergeContainerImages(owned.Spec.Containers, template.Spec.Containers)
+	return owned
+}
+
+// mergeContainerImages updates the images of owned containers and appends the containers that
+// are not owned yet
+func mergeContainerImages(owned, updated []corev1ac.ContainerApplyConfiguration) []corev1ac.ContainerApplyConfiguration {
+	for _, container := range updated {
+		i := slices.IndexFunc(owned, func(c corev1ac.ContainerApplyConfiguration) bool {
+			return c.Name != nil && *c.Name == *container.Name
+		})
+		if i < 0 {
+			owned = append(owned, container)
+			continue
+		}
+		owned[i].Image = container.Image
+	}
+	return owned
+}
+
+// fieldManager returns the configured field manager name
+func (c *Client) fieldManager() string {
+	if c.updateOptions.FieldManager == "" {
+		return DefaultFieldManager
+	}
+	return c.updateOptions.FieldManager
+}
+
+// describeConflict turns a server-side apply conflict into a readable error listing the other managers
+func describeConflict(w Workload, err error) error {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return fmt.Errorf("conflict updating %s: %v", w, err)
+	}
+
+	var lines []string
+	for _, cause := range status.Status().Details.Causes {
+		lines = append(lines, fmt.Sprintf("  %s: %s", cause.Field, cause.Message))
+	}
+
+	return fmt.Errorf("fields of %s are managed by another field manager:\n%s\n"+
+		"Update the image through that manager (e.g. Helm values or the Argo CD source), "+
+		"or re-run with --force-conflicts to take ownership of these fields",
+		w, strings.Join(lines, "\n"))
+}
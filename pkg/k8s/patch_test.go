@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"encoding/json"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestServerSideApplyKeepsOwnedImages(t *testing.T) {
+	// An earlier run with --force-conflicts became the only owner of the image of container "app"
+	deployment := testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{})
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "app", Image: "myorg/app:v2"},
+		{Name: "sidecar", Image: "envoy:1.29"},
+	}
+	deployment.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:    DefaultFieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{` +
+			`"k:{\"name\":\"app\"}":{".":{},"f:image":{},"f:name":{}}}}}}}`)},
+	}}
+
+	clientset := fake.NewSimpleClientset(deployment)
+	var applied *appsv1.Deployment
+	clientset.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			t.Errorf("patch type = %s, want server-side apply", patch.GetPatchType())
+		}
+		applied = &appsv1.Deployment{}
+		if err := json.Unmarshal(patch.GetPatch(), applied); err != nil {
+			t.Fatalf("failed to decode applied configuration: %v", err)
+		}
+		return false, nil, nil
+	})
+
+	client := &Client{clientset: clientset, namespace: testNamespace}
+	client.SetUpdateOptions(UpdateOptions{ServerSide: true})
+
+	// This run only changes the sidecar
+	if err := client.UpdateContainerImages(Workload{Kind: KindDeployment, Name: "web"}, map[string]string{"sidecar": "envoy:1.30"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if applied == nil {
+		t.Fatal("nothing was applied")
+	}
+	images := map[string]string{}
+	for _, container := range applied.Spec.Template.Spec.Containers {
+		images[container.Name] = container.Image
+	}
+	if images["app"] != "myorg/app:v2" || images["sidecar"] != "envoy:1.30" || len(images) != 2 {
+		t.Errorf("applied images = %v, want the owned app image kept next to the new sidecar image", images)
+	}
+	if applied.Annotations[imagesAnnotation] != "sidecar=envoy:1.30" {
+		t.Errorf("applied %s = %q, want the images of this run", imagesAnnotation, applied.Annotations[imagesAnnotation])
+	}
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// ResolveWorkloads resolves kubectl-style resource arguments (TYPE/NAME or TYPE NAME...)
+// and manifest files into workloads, fetching each one to make sure it exists
+func (c *Client) ResolveWorkloads(args []string, filenames *resource.FilenameOptions) ([]Workload, error) {
+	r := resource.NewBuilder(c.restClientGetter).
+		Unstructured().
+		ContinueOnError().
+		NamespaceParam(c.namespace).DefaultNamespace().
+		FilenameParam(c.enforceNamespace, filenames).
+		ResourceTypeOrNameArgs(false, args...).
+		RequireObject(true).
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	infos, err := r.Infos()
+	if err != nil {
+		return nil, err
+	}
+
+	var workloads []Workload
+	for _, info := range infos {
+		kind := WorkloadKind(info.Mapping.GroupVersionKind.Kind)
+		if info.Mapping.GroupVersionKind.GroupKind() == batchv1.SchemeGroupVersion.WithKind("Job").GroupKind() {
+			return nil, fmt.Errorf("job.batch/%s: Job pod templates are immutable; update the CronJob or recreate the Job", info.Name)
+		}
+		if !kind.supported() {
+			return nil, fmt.Errorf("%s/%s is not a pod-template workload", info.Mapping.Resource.Resource, info.Name)
+		}
+		workloads = append(workloads, Workload{
+			Kind:      kind,
+			Name:      info.Name,
+			Namespace: info.Namespace,
+		})
+	}
+
+	if len(workloads) == 0 {
+		return nil, fmt.Errorf("no workloads found")
+	}
+
+	return workloads, nil
+}
+
+// IsResourceType reports whether an argument names a resource type, such as "deployment",
+// "deploy" or "statefulsets.apps", the way the resource builder reads TYPE NAME arguments
+func (c *Client) IsResourceType(arg string) bool {
+	mapper, err := c.restClientGetter.ToRESTMapper()
+	if err != nil {
+		return false
+	}
+	_, err = mapper.ResourceFor(schema.ParseGroupResource(arg).WithVersion(""))
+	return err == nil
+}
+
+// supported reports whether the kind is one of WorkloadKinds
+func (k WorkloadKind) supported() bool {
+	for _, kind := range WorkloadKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// changeCauseAnnotation holds the reason for a rollout, shown by kubectl rollout history
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+// Revision is a Deployment rollout revision backed by a ReplicaSet
+type Revision struct {
+	Number      int64
+	ReplicaSet  string
+	Containers  []ContainerInfo
+	ChangeCause string
+	CreatedAt   time.Time
+	Current     bool
+
+	annotations map[string]string
+}
+
+// ListRevisions returns the rollout revisions of a deployment, newest first
+func (c *Client) ListRevisions(w Workload) ([]Revision, error) {
+	ctx := context.Background()
+
+	if w.Kind != KindDeployment {
+		return nil, fmt.Errorf("revisions are only supported for deployments, not %s", w)
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %v", w, err)
+	}
+
+	replicaSets, err := c.ownedReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	currentRevision := deployment.Annotations[revisionAnnotation]
+	var revisions []Revision
+	for _, rs := range replicaSets {
+		number, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		spec := rs.Spec.Template.Spec
+		var containers []ContainerInfo
+		for i, container := range spec.InitContainers {
+			containers = append(containers, ContainerInfo{Name: container.Name, Image: container.Image, Index: i, Type: ContainerTypeInit})
+		}
+		for i, container := range spec.Containers {
+			containers = append(containers, ContainerInfo{Name: container.Name, Image: container.Image, Index: i, Type: ContainerTypeRegular})
+		}
+
+		revisions = append(revisions, Revision{
+			Number:      number,
+			ReplicaSet:  rs.Name,
+			Containers:  containers,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			CreatedAt:   rs.CreationTimestamp.Time,
+			Current:     rs.Annotations[revisionAnnotation] == currentRevision,
+			annotations: rs.Annotations,
+		})
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number > revisions[j].Number
+	})
+
+	return revisions, nil
+}
+
+// RollbackToRevision restores the pod template of a deployment revision, like
+// kubectl rollout undo --to-revision. The deployment controller then re-labels
+// the restored template as the newest revision.
+func (c *Client) RollbackToRevision(w Workload, number int64) error {
+	ctx := context.Background()
+
+	if w.Kind != KindDeployment {
+		return fmt.Errorf("revisions are only supported for deployments, not %s", w)
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %v", w, err)
+	}
+	if deployment.Spec.Paused {
+		return fmt.Errorf("%s: %w", w, ErrPaused)
+	}
+
+	replicaSets, err := c.ownedReplicaSets(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	var target *appsv1.ReplicaSet
+	for _, rs := range replicaSets {
+		if rs.Annotations[revisionAnnotation] == strconv.FormatInt(number, 10) {
+			target = rs
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("revision %d of %s not found", number, w)
+	}
+	if target.Annotations[revisionAnnotation] == deployment.Annotations[revisionAnnotation] {
+		return fmt.Errorf("revision %d is already the current revision of %s", number, w)
+	}
+
+	// The pod-template-hash label is added by the deployment controller
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	images := make(map[string]string)
+	for _, container := range append(template.Spec.InitContainers, template.Spec.Containers...) {
+		images[container.Name] = container.Image
+	}
+	info := c.changeInfo
+	if info.Reason == "" {
+		info.Reason = fmt.Sprintf("rollback to revision %d", number)
+	}
+	annotations := changeAnnotations(info, deployment.Spec.Template.Spec, images)
+
+	ops := []map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	}
+	if deployment.Annotations == nil {
+		ops = append(ops, map[string]interface{}{
+			"op": "add", "path": "/metadata/annotations", "value": annotations,
+		})
+	} else {
+		for _, key := range sortedAnnotationKeys(annotations) {
+			ops = append(ops, map[string]interface{}{
+				"op": "add", "path": "/metadata/annotations/" + escapeJSONPointer(key), "value": annotations[key],
+			})
+		}
+	}
+
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("failed to build patch: %v", err)
+	}
+
+	_, err = c.clientset.AppsV1().Deployments(c.namespaceOf(w)).Patch(ctx, w.Name, types.JSONPatchType, data,
+		metav1.PatchOptions{FieldManager: c.fieldManager()})
+	if err != nil {
+		return fmt.Errorf("failed to roll back %s to revision %d: %v", w, number, err)
+	}
+
+	return nil
+}
+
+// ownedReplicaSets lists the ReplicaSets controlled by a deployment
+func (c *Client) ownedReplicaSets(ctx context.Context, deployment *appsv1.Deployment) ([]*appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+
+	src := &apiSource{ctx: ctx, clientset: c.clientset}
+	replicaSets, err := src.listReplicaSets(deployment.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets of deployment %s: %v", deployment.Name, err)
+	}
+
+	var owned []*appsv1.ReplicaSet
+	for _, rs := range replicaSets {
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// sortedAnnotationKeys returns annotation keys in a stable order
+func sortedAnnotationKeys(annotations map[string]string) []string {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeJSONPointer escapes a key for use in a JSON patch path
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
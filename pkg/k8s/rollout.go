@@ -0,0 +1,309 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// objectSource reads the objects a rollout is evaluated from, either straight
+// from the API server or from an informer cache
+type objectSource interface {
+	getDeployment(namespace, name string) (*appsv1.Deployment, error)
+	getStatefulSet(namespace, name string) (*appsv1.StatefulSet, error)
+	getDaemonSet(namespace, name string) (*appsv1.DaemonSet, error)
+	getReplicaSet(namespace, name string) (*appsv1.ReplicaSet, error)
+	getCronJob(namespace, name string) (*batchv1.CronJob, error)
+	listReplicaSets(namespace string, selector labels.Selector) ([]*appsv1.ReplicaSet, error)
+	listPods(namespace string, selector labels.Selector) ([]*corev1.Pod, error)
+}
+
+// rolloutStatus is the result of evaluating a workload's rollout
+type rolloutStatus struct {
+	done bool
+
+	// message describes the progress while the rollout is not done
+	message string
+
+	// selector matches the pods to check for failures while the rollout is not done
+	selector *metav1.LabelSelector
+}
+
+// CheckReadiness checks if a workload has finished rolling out its pod template
+func (c *Client) CheckReadiness(w Workload) (bool, error) {
+	src := &apiSource{ctx: context.Background(), clientset: c.clientset}
+	namespace := c.namespaceOf(w)
+
+	status, err := evaluateRollout(src, w, namespace)
+	if err != nil || status.done || status.selector == nil {
+		return status.done, err
+	}
+
+	pods, err := listSelectedPods(src, namespace, status.selector)
+	if err != nil {
+		return false, err
+	}
+	return false, c.rules().check(pods, nil, time.Now())
+}
+
+// evaluateRollout evaluates the rollout of a workload with the readiness semantics of its kind
+func evaluateRollout(src objectSource, w Workload, namespace string) (rolloutStatus, error) {
+	switch w.Kind {
+	case KindDeployment:
+		return deploymentRollout(src, w, namespace)
+	case KindStatefulSet:
+		return statefulSetRollout(src, w, namespace)
+	case KindDaemonSet:
+		return daemonSetRollout(src, w, namespace)
+	case KindReplicaSet:
+		return replicaSetRollout(src, w, namespace)
+	case KindCronJob:
+		// A CronJob has nothing to roll out; the new template is used by the next scheduled run
+		_, err := src.getCronJob(namespace, w.Name)
+		return rolloutStatus{done: err == nil}, err
+	}
+	return rolloutStatus{}, fmt.Errorf("unsupported workload kind: %s", w.Kind)
+}
+
+// deploymentRollout reports whether a deployment has finished rolling out, following
+// kubectl rollout status. While the rollout is in progress the returned selector matches
+// only the pods of the new ReplicaSet.
+func deploymentRollout(src objectSource, w Workload, namespace string) (rolloutStatus, error) {
+	deployment, err := src.getDeployment(namespace, w.Name)
+	if err != nil {
+		return rolloutStatus{}, err
+	}
+
+	if deployment.Spec.Paused {
+		return rolloutStatus{}, fmt.Errorf("%s: %w", w, ErrPaused)
+	}
+
+	// Wait for the controller to observe the patched spec
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return rolloutStatus{message: "waiting for the deployment spec update to be observed"}, nil
+	}
+
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return rolloutStatus{}, fmt.Errorf("%s exceeded its progress deadline", w)
+		}
+	}
+
+	replicas := replicasOrDefault(deployment.Spec.Replicas)
+	status := deployment.Status
+	var message string
+	switch {
+	case status.UpdatedReplicas < replicas:
+		message = fmt.Sprintf("%d out of %d new replicas have been updated", status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		message = fmt.Sprintf("%d old replicas are pending termination", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		message = fmt.Sprintf("%d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas)
+	default:
+		return rolloutStatus{done: true}, nil
+	}
+
+	rs, err := newReplicaSet(src, deployment)
+	if err != nil || rs == nil {
+		return rolloutStatus{message: message}, err
+	}
+
+	selector := deployment.Spec.Selector.DeepCopy()
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = map[string]string{}
+	}
+	selector.MatchLabels[appsv1.DefaultDeploymentUniqueLabelKey] = rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	return rolloutStatus{message: message, selector: selector}, nil
+}
+
+// newReplicaSet finds the ReplicaSet of the deployment's current revision, or nil if it does not exist yet
+func newReplicaSet(src objectSource, deployment *appsv1.Deployment) (*appsv1.ReplicaSet, error) {
+	revision := deployment.Annotations[revisionAnnotation]
+	if revision == "" {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+
+	replicaSets, err := src.listReplicaSets(deployment.Namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rs := range replicaSets {
+		owner := metav1.GetControllerOf(rs)
+		if owner == nil || owner.UID != deployment.UID {
+			continue
+		}
+		if rs.Annotations[revisionAnnotation] == revision {
+			return rs, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// statefulSetRollout reports whether a statefulset has moved all pods to its update revision
+func statefulSetRollout(src objectSource, w Workload, namespace string) (rolloutStatus, error) {
+	sts, err := src.getStatefulSet(namespace, w.Name)
+	if err != nil {
+		return rolloutStatus{}, err
+	}
+
+	// Like kubectl rollout status, only rolling updates can be watched
+	if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return rolloutStatus{}, fmt.Errorf("%s: %w", w, ErrOnDelete)
+	}
+
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return rolloutStatus{message: "waiting for the statefulset spec update to be observed", selector: sts.Spec.Selector}, nil
+	}
+
+	replicas := replicasOrDefault(sts.Spec.Replicas)
+	if sts.Status.ReadyReplicas != replicas {
+		return rolloutStatus{
+			message:  fmt.Sprintf("%d of %d replicas are ready", sts.Status.ReadyReplicas, replicas),
+			selector: sts.Spec.Selector,
+		}, nil
+	}
+
+	// With a partitioned rolling update only ordinals >= partition get the new revision
+	if rolling := sts.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil && *rolling.Partition > 0 {
+		if sts.Status.UpdatedReplicas >= replicas-*rolling.Partition {
+			return rolloutStatus{done: true}, nil
+		}
+		return rolloutStatus{
+			message:  fmt.Sprintf("%d of %d partitioned replicas have been updated", sts.Status.UpdatedReplicas, replicas-*rolling.Partition),
+			selector: sts.Spec.Selector,
+		}, nil
+	}
+
+	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		return rolloutStatus{
+			message:  fmt.Sprintf("%d of %d replicas have been updated to revision %s", sts.Status.UpdatedReplicas, replicas, sts.Status.UpdateRevision),
+			selector: sts.Spec.Selector,
+		}, nil
+	}
+
+	return rolloutStatus{done: true}, nil
+}
+
+// daemonSetRollout reports whether a daemonset has updated and made available a pod on every node
+func daemonSetRollout(src objectSource, w Workload, namespace string) (rolloutStatus, error) {
+	ds, err := src.getDaemonSet(namespace, w.Name)
+	if err != nil {
+		return rolloutStatus{}, err
+	}
+
+	if ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return rolloutStatus{}, fmt.Errorf("%s: %w", w, ErrOnDelete)
+	}
+
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return rolloutStatus{message: "waiting for the daemonset spec update to be observed", selector: ds.Spec.Selector}, nil
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+	switch {
+	case ds.Status.UpdatedNumberScheduled < desired:
+		return rolloutStatus{
+			message:  fmt.Sprintf("%d out of %d new pods have been updated", ds.Status.UpdatedNumberScheduled, desired),
+			selector: ds.Spec.Selector,
+		}, nil
+	case ds.Status.NumberAvailable < desired:
+		return rolloutStatus{
+			message:  fmt.Sprintf("%d of %d updated pods are available", ds.Status.NumberAvailable, desired),
+			selector: ds.Spec.Selector,
+		}, nil
+	}
+
+	return rolloutStatus{done: true}, nil
+}
+
+// replicaSetRollout reports whether all replicas of a replicaset are ready
+func replicaSetRollout(src objectSource, w Workload, namespace string) (rolloutStatus, error) {
+	rs, err := src.getReplicaSet(namespace, w.Name)
+	if err != nil {
+		return rolloutStatus{}, err
+	}
+
+	// A ReplicaSet does not replace existing pods when its template changes
+	replicas := replicasOrDefault(rs.Spec.Replicas)
+	if rs.Status.ObservedGeneration >= rs.Generation && rs.Status.ReadyReplicas == replicas {
+		return rolloutStatus{done: true}, nil
+	}
+
+	return rolloutStatus{
+		message:  fmt.Sprintf("%d of %d replicas are ready", rs.Status.ReadyReplicas, replicas),
+		selector: rs.Spec.Selector,
+	}, nil
+}
+
+// listSelectedPods lists the pods matching a label selector
+func listSelectedPods(src objectSource, namespace string, labelSelector *metav1.LabelSelector) ([]*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	return src.listPods(namespace, selector)
+}
+
+// apiSource reads objects straight from the API server
+type apiSource struct {
+	ctx       context.Context
+	clientset kubernetes.Interface
+}
+
+func (s *apiSource) getDeployment(namespace, name string) (*appsv1.Deployment, error) {
+	return s.clientset.AppsV1().Deployments(namespace).Get(s.ctx, name, metav1.GetOptions{})
+}
+
+func (s *apiSource) getStatefulSet(namespace, name string) (*appsv1.StatefulSet, error) {
+	return s.clientset.AppsV1().StatefulSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+}
+
+func (s *apiSource) getDaemonSet(namespace, name string) (*appsv1.DaemonSet, error) {
+	return s.clientset.AppsV1().DaemonSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+}
+
+func (s *apiSource) getReplicaSet(namespace, name string) (*appsv1.ReplicaSet, error) {
+	return s.clientset.AppsV1().ReplicaSets(namespace).Get(s.ctx, name, metav1.GetOptions{})
+}
+
+func (s *apiSource) getCronJob(namespace, name string) (*batchv1.CronJob, error) {
+	return s.clientset.BatchV1().CronJobs(namespace).Get(s.ctx, name, metav1.GetOptions{})
+}
+
+func (s *apiSource) listReplicaSets(namespace string, selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+	list, err := s.clientset.AppsV1().ReplicaSets(namespace).List(s.ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*appsv1.ReplicaSet, len(list.Items))
+	for i := range list.Items {
+		items[i] = &list.Items[i]
+	}
+	return items, nil
+}
+
+func (s *apiSource) listPods(namespace string, selector labels.Selector) ([]*corev1.Pod, error) {
+	list, err := s.clientset.CoreV1().Pods(namespace).List(s.ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		items[i] = &list.Items[i]
+	}
+	return items, nil
+}
@@ -0,0 +1,280 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "default"
+
+// ptrTo returns a pointer to v
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+var testSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+// testMeta returns the object meta of a workload named "web" at generation 2
+func testMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: "web", Namespace: testNamespace, Generation: 2, UID: types.UID("web-uid")}
+}
+
+// testDeployment returns a deployment at revision 2 whose controller observed the spec
+func testDeployment(replicas *int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	meta := testMeta()
+	meta.Annotations = map[string]string{revisionAnnotation: "2"}
+	status.ObservedGeneration = 2
+	return &appsv1.Deployment{
+		ObjectMeta: meta,
+		Spec:       appsv1.DeploymentSpec{Replicas: replicas, Selector: testSelector},
+		Status:     status,
+	}
+}
+
+// testReplicaSet returns the ReplicaSet of revision 2 of the deployment "web"
+func testReplicaSet() *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web-abc123",
+			Namespace:   testNamespace,
+			Labels:      map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "abc123"},
+			Annotations: map[string]string{revisionAnnotation: "2"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "web-uid", Controller: ptrTo(true),
+			}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Selector: testSelector},
+	}
+}
+
+func TestEvaluateRollout(t *testing.T) {
+	tests := []struct {
+		name         string
+		kind         WorkloadKind
+		objects      []runtime.Object
+		wantDone     bool
+		wantErr      bool
+		wantPaused   bool
+		wantOnDelete bool
+		wantMessage  string
+		wantHash     string
+	}{
+		{
+			name:     "deployment rolled out",
+			kind:     KindDeployment,
+			objects:  []runtime.Object{testDeployment(ptrTo[int32](2), appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2})},
+			wantDone: true,
+		},
+		{
+			name:     "deployment with nil replicas rolled out",
+			kind:     KindDeployment,
+			objects:  []runtime.Object{testDeployment(nil, appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1})},
+			wantDone: true,
+		},
+		{
+			name:        "deployment with nil replicas in progress",
+			kind:        KindDeployment,
+			objects:     []runtime.Object{testDeployment(nil, appsv1.DeploymentStatus{Replicas: 1}), testReplicaSet()},
+			wantMessage: "0 out of 1 new replicas have been updated",
+			wantHash:    "abc123",
+		},
+		{
+			name:        "deployment terminating old replicas",
+			kind:        KindDeployment,
+			objects:     []runtime.Object{testDeployment(ptrTo[int32](2), appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}), testReplicaSet()},
+			wantMessage: "1 old replicas are pending termination",
+			wantHash:    "abc123",
+		},
+		{
+			name: "deployment spec not observed yet",
+			kind: KindDeployment,
+			objects: []runtime.Object{func() runtime.Object {
+				d := testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1})
+				d.Status.ObservedGeneration = 1
+				return d
+			}()},
+			wantMessage: "waiting for the deployment spec update to be observed",
+		},
+		{
+			name: "paused deployment",
+			kind: KindDeployment,
+			objects: []runtime.Object{func() runtime.Object {
+				d := testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{})
+				d.Spec.Paused = true
+				return d
+			}()},
+			wantErr:    true,
+			wantPaused: true,
+		},
+		{
+			name: "deployment exceeded its progress deadline",
+			kind: KindDeployment,
+			objects: []runtime.Object{testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{
+				Replicas: 2, UpdatedReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+				}},
+			})},
+			wantErr: true,
+		},
+		{
+			name: "statefulset updating",
+			kind: KindStatefulSet,
+			objects: []runtime.Object{&appsv1.StatefulSet{
+				ObjectMeta: testMeta(),
+				Spec:       appsv1.StatefulSetSpec{Replicas: ptrTo[int32](3), Selector: testSelector},
+				Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 1,
+					CurrentRevision: "web-1", UpdateRevision: "web-2"},
+			}},
+			wantMessage: "1 of 3 replicas have been updated to revision web-2",
+		},
+		{
+			name: "statefulset rolled out",
+			kind: KindStatefulSet,
+			objects: []runtime.Object{&appsv1.StatefulSet{
+				ObjectMeta: testMeta(),
+				Spec:       appsv1.StatefulSetSpec{Selector: testSelector},
+				Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 1, UpdatedReplicas: 1,
+					CurrentRevision: "web-2", UpdateRevision: "web-2"},
+			}},
+			wantDone: true,
+		},
+		{
+			name: "statefulset with the OnDelete strategy",
+			kind: KindStatefulSet,
+			objects: []runtime.Object{&appsv1.StatefulSet{
+				ObjectMeta: testMeta(),
+				Spec: appsv1.StatefulSetSpec{Selector: testSelector,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}},
+				Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 1,
+					CurrentRevision: "web-1", UpdateRevision: "web-2"},
+			}},
+			wantErr:      true,
+			wantOnDelete: true,
+		},
+		{
+			name: "daemonset with the OnDelete strategy",
+			kind: KindDaemonSet,
+			objects: []runtime.Object{&appsv1.DaemonSet{
+				ObjectMeta: testMeta(),
+				Spec: appsv1.DaemonSetSpec{Selector: testSelector,
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}},
+				Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, NumberAvailable: 3},
+			}},
+			wantErr:      true,
+			wantOnDelete: true,
+		},
+		{
+			name: "daemonset updating",
+			kind: KindDaemonSet,
+			objects: []runtime.Object{&appsv1.DaemonSet{
+				ObjectMeta: testMeta(),
+				Spec:       appsv1.DaemonSetSpec{Selector: testSelector},
+				Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			}},
+			wantMessage: "2 of 3 updated pods are available",
+		},
+		{
+			name: "daemonset rolled out",
+			kind: KindDaemonSet,
+			objects: []runtime.Object{&appsv1.DaemonSet{
+				ObjectMeta: testMeta(),
+				Spec:       appsv1.DaemonSetSpec{Selector: testSelector},
+				Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			}},
+			wantDone: true,
+		},
+		{
+			name: "replicaset with nil replicas ready",
+			kind: KindReplicaSet,
+			objects: []runtime.Object{&appsv1.ReplicaSet{
+				ObjectMeta: testMeta(),
+				Spec:       appsv1.ReplicaSetSpec{Selector: testSelector},
+				Status:     appsv1.ReplicaSetStatus{ObservedGeneration: 2, ReadyReplicas: 1},
+			}},
+			wantDone: true,
+		},
+		{
+			name:     "cronjob",
+			kind:     KindCronJob,
+			objects:  []runtime.Object{&batchv1.CronJob{ObjectMeta: testMeta()}},
+			wantDone: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &apiSource{ctx: context.Background(), clientset: fake.NewSimpleClientset(tt.objects...)}
+			status, err := evaluateRollout(src, Workload{Kind: tt.kind, Name: "web"}, testNamespace)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateRollout() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantPaused && !errors.Is(err, ErrPaused) {
+				t.Errorf("evaluateRollout() error = %v, want ErrPaused", err)
+			}
+			if tt.wantOnDelete && !errors.Is(err, ErrOnDelete) {
+				t.Errorf("evaluateRollout() error = %v, want ErrOnDelete", err)
+			}
+			if status.done != tt.wantDone {
+				t.Errorf("done = %v, want %v", status.done, tt.wantDone)
+			}
+			if status.message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", status.message, tt.wantMessage)
+			}
+			if tt.wantHash != "" {
+				if status.selector == nil || status.selector.MatchLabels[appsv1.DefaultDeploymentUniqueLabelKey] != tt.wantHash {
+					t.Errorf("selector = %v, want the pods of ReplicaSet hash %s", status.selector, tt.wantHash)
+				}
+			}
+		})
+	}
+}
+
+func TestStatefulSetPartition(t *testing.T) {
+	// With partition 2 of 3 replicas, only ordinal 2 gets the new revision
+	sts := func(updated int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: testMeta(),
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: ptrTo[int32](3),
+				Selector: testSelector,
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptrTo[int32](2)},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: updated,
+				CurrentRevision: "web-1", UpdateRevision: "web-2"},
+		}
+	}
+	w := Workload{Kind: KindStatefulSet, Name: "web"}
+
+	src := &apiSource{ctx: context.Background(), clientset: fake.NewSimpleClientset(sts(0))}
+	status, err := evaluateRollout(src, w, testNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.done || status.message != "0 of 1 partitioned replicas have been updated" {
+		t.Errorf("before the partition is updated: done = %v, message = %q", status.done, status.message)
+	}
+
+	// The revisions still differ, as ordinals below the partition keep the old one
+	src = &apiSource{ctx: context.Background(), clientset: fake.NewSimpleClientset(sts(1))}
+	status, err = evaluateRollout(src, w, testNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.done {
+		t.Errorf("after the partition is updated: done = false, message = %q", status.message)
+	}
+}
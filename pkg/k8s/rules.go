@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultFatalReasons are container waiting or termination reasons that fail a rollout
+var defaultFatalReasons = []string{
+	"ImagePullBackOff",
+	"ErrImagePull",
+	"InvalidImageName",
+	"CrashLoopBackOff",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"OOMKilled",
+}
+
+// FailureRules decides which pod states make a rollout fail in watch mode
+type FailureRules struct {
+	// MaxRestarts is the number of restarts per container allowed since the update; negative disables the check
+	MaxRestarts int32
+
+	// FatalReasons are container waiting or termination reasons that fail the rollout
+	FatalReasons []string
+
+	// NotReadyTimeout is how long a running container may stay unready, e.g. because its
+	// readiness probe keeps failing; zero disables the check
+	NotReadyTimeout time.Duration
+}
+
+// DefaultFailureRules returns the failure rules used when nothing is configured
+func DefaultFailureRules() FailureRules {
+	return FailureRules{
+		MaxRestarts:     3,
+		FatalReasons:    append([]string(nil), defaultFatalReasons...),
+		NotReadyTimeout: 2 * time.Minute,
+	}
+}
+
+// failureRulesFile is the format of a failure rules config file. Unset fields keep their
+// defaults and fatalReasons are added to the default reasons.
+type failureRulesFile struct {
+	MaxRestarts     *int32           `json:"maxRestarts,omitempty"`
+	FatalReasons    []string         `json:"fatalReasons,omitempty"`
+	NotReadyTimeout *metav1.Duration `json:"notReadyTimeout,omitempty"`
+}
+
+// LoadFailureRules reads failure rules from a YAML or JSON file on top of the defaults
+func LoadFailureRules(path string) (FailureRules, error) {
+	rules := DefaultFailureRules()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("failed to read failure rules %s: %v", path, err)
+	}
+
+	var file failureRulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return rules, fmt.Errorf("failed to parse failure rules %s: %v", path, err)
+	}
+
+	if file.MaxRestarts != nil {
+		rules.MaxRestarts = *file.MaxRestarts
+	}
+	rules.FatalReasons = append(rules.FatalReasons, file.FatalReasons...)
+	if file.NotReadyTimeout != nil {
+		rules.NotReadyTimeout = file.NotReadyTimeout.Duration
+	}
+
+	return rules, nil
+}
+
+// SetFailureRules sets the rules used to detect failed rollouts
+func (c *Client) SetFailureRules(rules FailureRules) {
+	c.failureRules = &rules
+}
+
+// rules returns the configured failure rules, or the defaults
+func (c *Client) rules() FailureRules {
+	if c.failureRules == nil {
+		return DefaultFailureRules()
+	}
+	return *c.failureRules
+}
+
+// RestartBaseline holds container restart counts of a workload's pods, keyed by "podUID/container".
+// The bare pod UID marks a pod as present, and keying by UID makes a StatefulSet pod recreated
+// under the same name count as new. Restarts are counted from the baseline, and pods in it are
+// only checked once they restart again, so pods that were already flaky or unready do not fail
+// the rollout.
+type RestartBaseline map[string]int32
+
+// baselineKey returns the RestartBaseline key of a container in a pod
+func baselineKey(pod *corev1.Pod, container string) string {
+	return string(pod.UID) + "/" + container
+}
+
+// GetRestartBaseline records the current container restart counts of a workload's pods
+func (c *Client) GetRestartBaseline(w Workload) (RestartBaseline, error) {
+	ctx := context.Background()
+
+	pods, err := c.selectPods(ctx, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s: %v", w, err)
+	}
+
+	baseline := RestartBaseline{}
+	for _, pod := range pods {
+		baseline[string(pod.UID)] = 0
+		for _, status := range containerStatuses(pod) {
+			baseline[baselineKey(pod, status.Name)] = status.RestartCount
+		}
+	}
+
+	return baseline, nil
+}
+
+// check looks for pods that fail the rules
+func (r FailureRules) check(pods []*corev1.Pod, baseline RestartBaseline, now time.Time) error {
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodFailed {
+			// Old failed pods, e.g. of a CronJob run, stay around and weren't failed by the update
+			if _, existed := baseline[string(pod.UID)]; existed {
+				continue
+			}
+			return fmt.Errorf("pod %s failed", pod.Name)
+		}
+
+		for _, status := range containerStatuses(pod) {
+			base, existed := baseline[baselineKey(pod, status.Name)]
+			restarts := status.RestartCount - base
+			if r.MaxRestarts >= 0 && restarts > r.MaxRestarts {
+				return fmt.Errorf("container %s in pod %s restarted %d times since the update (max %d)",
+					status.Name, pod.Name, restarts, r.MaxRestarts)
+			}
+
+			// Selectors of StatefulSets, DaemonSets, ReplicaSets and Jobs also match pods from before
+			// the update, whose old termination reasons weren't caused by it
+			if existed && restarts <= 0 {
+				continue
+			}
+
+			if waiting := status.State.Waiting; waiting != nil && r.isFatal(waiting.Reason) {
+				return fmt.Errorf("container %s in pod %s has problem: %s", status.Name, pod.Name, waiting.Reason)
+			}
+
+			for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated != nil && r.isFatal(terminated.Reason) {
+					return fmt.Errorf("container %s in pod %s terminated: %s", status.Name, pod.Name, terminated.Reason)
+				}
+			}
+
+			// A pod that was unready before the update may have been unready for long already
+			if running := status.State.Running; running != nil && !status.Ready && r.NotReadyTimeout > 0 && !existed {
+				if notReady := now.Sub(running.StartedAt.Time); notReady > r.NotReadyTimeout {
+					return fmt.Errorf("container %s in pod %s has not become ready for %v (failing readiness probe?)",
+						status.Name, pod.Name, notReady.Round(time.Second))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isFatal reports whether a waiting or termination reason fails the rollout
+func (r FailureRules) isFatal(reason string) bool {
+	for _, fatal := range r.FatalReasons {
+		if reason == fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// containerStatuses returns the statuses of a pod's init containers and containers
+func containerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	statuses := append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
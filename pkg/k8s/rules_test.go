@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testPod returns a running pod with one container "app"
+func testPod(uid string, status corev1.ContainerStatus) *corev1.Pod {
+	status.Name = "app"
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", UID: types.UID(uid)},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{status},
+		},
+	}
+}
+
+func TestFailureRulesCheck(t *testing.T) {
+	now := time.Now()
+	oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}}
+	runningSince := func(d time.Duration) corev1.ContainerState {
+		return corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(now.Add(-d))}}
+	}
+	// The old pod "old" had restarted twice before the update
+	baseline := RestartBaseline{"old": 0, "old/app": 2}
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		wantFail bool
+	}{
+		{"old pod OOMKilled before the update", testPod("old", corev1.ContainerStatus{
+			RestartCount: 2, LastTerminationState: oomKilled, State: runningSince(time.Hour), Ready: true}), false},
+		{"old pod OOMKilled again", testPod("old", corev1.ContainerStatus{
+			RestartCount: 3, LastTerminationState: oomKilled, State: runningSince(time.Second), Ready: true}), true},
+		{"old pod already unready", testPod("old", corev1.ContainerStatus{
+			RestartCount: 2, State: runningSince(time.Hour)}), false},
+		{"old pod restarted within the limit", testPod("old", corev1.ContainerStatus{
+			RestartCount: 5, State: runningSince(time.Second), Ready: true}), false},
+		{"old pod restarted too often", testPod("old", corev1.ContainerStatus{
+			RestartCount: 6, State: runningSince(time.Second), Ready: true}), true},
+		{"recreated pod with the same name OOMKilled", testPod("new", corev1.ContainerStatus{
+			RestartCount: 1, LastTerminationState: oomKilled, State: runningSince(time.Second)}), true},
+		{"new pod unready too long", testPod("new", corev1.ContainerStatus{
+			State: runningSince(time.Hour)}), true},
+		{"new pod pulling a missing image", testPod("new", corev1.ContainerStatus{
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}), true},
+		{"new pod healthy", testPod("new", corev1.ContainerStatus{
+			State: runningSince(time.Hour), Ready: true}), false},
+	}
+
+	rules := DefaultFailureRules()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rules.check([]*corev1.Pod{tt.pod}, baseline, now)
+			if (err != nil) != tt.wantFail {
+				t.Errorf("check() = %v, want failure %v", err, tt.wantFail)
+			}
+		})
+	}
+
+	t.Run("failed pods", func(t *testing.T) {
+		old := testPod("old", corev1.ContainerStatus{RestartCount: 2})
+		old.Status.Phase = corev1.PodFailed
+		if err := rules.check([]*corev1.Pod{old}, baseline, now); err != nil {
+			t.Errorf("old failed pod: check() = %v, want nil", err)
+		}
+
+		created := testPod("new", corev1.ContainerStatus{})
+		created.Status.Phase = corev1.PodFailed
+		if err := rules.check([]*corev1.Pod{created}, baseline, now); err == nil {
+			t.Error("new failed pod: check() = nil, want a failure")
+		}
+	})
+}
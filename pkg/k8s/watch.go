@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WatchWorkload monitors workload readiness with timeout. The rollout is re-evaluated from
+// shared informer caches whenever the workload, its ReplicaSets, its pods or their warning
+// events change, and progress messages and warning events are passed to progress as they
+// arrive. Container restarts are counted from baseline, which may be nil.
+func (c *Client) WatchWorkload(w Workload, timeout time.Duration, baseline RestartBaseline, progress func(message string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if progress == nil {
+		progress = func(string) {}
+	}
+	namespace := c.namespaceOf(w)
+	start := time.Now().Truncate(time.Second)
+
+	// The pod selector scopes the ReplicaSet and pod informers to this workload
+	_, labelSelector, err := c.getPodTemplate(ctx, w)
+	if err != nil {
+		return err
+	}
+	podSelector := labels.Everything()
+	if labelSelector != nil {
+		podSelector, err = metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+
+	workloadFactory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.Name).String()
+		}))
+	podFactory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = podSelector.String()
+		}))
+	eventFactory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("type", corev1.EventTypeWarning).String()
+		}))
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+
+	// Informers have to be registered before their factory is started
+	workloadInformer, err := informerFor(workloadFactory, w.Kind)
+	if err != nil {
+		return err
+	}
+	if _, err := workloadInformer.AddEventHandler(handler); err != nil {
+		return err
+	}
+	src := &cacheSource{
+		workloads:        workloadFactory,
+		pods:             podFactory,
+		watchReplicaSets: w.Kind == KindDeployment,
+		watchPods:        labelSelector != nil,
+	}
+	if src.watchReplicaSets {
+		if _, err := podFactory.Apps().V1().ReplicaSets().Informer().AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+	if src.watchPods {
+		if _, err := podFactory.Core().V1().Pods().Informer().AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+	reportEvent := func(obj interface{}) {
+		event, ok := obj.(*corev1.Event)
+		if !ok || eventTime(event).Before(start) || !src.involves(w, namespace, event.InvolvedObject) {
+			return
+		}
+		progress(fmt.Sprintf("%s %s/%s: %s", event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Message))
+
+		// Warning events such as failing probes do not always change pod status
+		notify()
+	}
+	if _, err := eventFactory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    reportEvent,
+		UpdateFunc: func(_, obj interface{}) { reportEvent(obj) },
+	}); err != nil {
+		return err
+	}
+
+	factories := []informers.SharedInformerFactory{workloadFactory, podFactory, eventFactory}
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+	}
+	defer func() {
+		// Stop the informers before waiting for them to shut down
+		cancel()
+		for _, factory := range factories {
+			factory.Shutdown()
+		}
+	}()
+	for _, factory := range []informers.SharedInformerFactory{workloadFactory, podFactory} {
+		for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return fmt.Errorf("timeout: cache for %v did not sync within %v", informerType, timeout)
+			}
+		}
+	}
+
+	rules := c.rules()
+	var lastMessage string
+	notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout: %s didn't become ready within %v", w, timeout)
+
+		case <-changed:
+			status, err := evaluateRollout(src, w, namespace)
+			if err != nil {
+				return err
+			}
+			if status.done {
+				return nil
+			}
+
+			if status.message != "" && status.message != lastMessage {
+				progress(fmt.Sprintf("Waiting for %s rollout to finish: %s", w, status.message))
+				lastMessage = status.message
+			}
+
+			if status.selector != nil {
+				pods, err := listSelectedPods(src, namespace, status.selector)
+				if err != nil {
+					return err
+				}
+				if err := rules.check(pods, baseline, time.Now()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// informerFor registers and returns the informer for a workload kind
+func informerFor(factory informers.SharedInformerFactory, kind WorkloadKind) (cache.SharedIndexInformer, error) {
+	switch kind {
+	case KindDeployment:
+		return factory.Apps().V1().Deployments().Informer(), nil
+	case KindStatefulSet:
+		return factory.Apps().V1().StatefulSets().Informer(), nil
+	case KindDaemonSet:
+		return factory.Apps().V1().DaemonSets().Informer(), nil
+	case KindReplicaSet:
+		return factory.Apps().V1().ReplicaSets().Informer(), nil
+	case KindCronJob:
+		return factory.Batch().V1().CronJobs().Informer(), nil
+	}
+	return nil, fmt.Errorf("unsupported workload kind: %s", kind)
+}
+
+// eventTime returns the most recent time an event was observed
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// cacheSource reads objects from informer caches. The workload factory holds the watched
+// workload; the pod factory holds the ReplicaSets and pods matching its selector.
+type cacheSource struct {
+	workloads        informers.SharedInformerFactory
+	pods             informers.SharedInformerFactory
+	watchReplicaSets bool
+	watchPods        bool
+}
+
+// involves reports whether an event's object is the workload or one of its ReplicaSets or pods
+func (s *cacheSource) involves(w Workload, namespace string, ref corev1.ObjectReference) bool {
+	switch ref.Kind {
+	case string(w.Kind):
+		return ref.Name == w.Name
+	case "ReplicaSet":
+		if !s.watchReplicaSets {
+			return false
+		}
+		_, err := s.pods.Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace).Get(ref.Name)
+		return err == nil
+	case "Pod":
+		if !s.watchPods {
+			return false
+		}
+		_, err := s.pods.Core().V1().Pods().Lister().Pods(namespace).Get(ref.Name)
+		return err == nil
+	}
+	return false
+}
+
+func (s *cacheSource) getDeployment(namespace, name string) (*appsv1.Deployment, error) {
+	return s.workloads.Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
+}
+
+func (s *cacheSource) getStatefulSet(namespace, name string) (*appsv1.StatefulSet, error) {
+	return s.workloads.Apps().V1().StatefulSets().Lister().StatefulSets(namespace).Get(name)
+}
+
+func (s *cacheSource) getDaemonSet(namespace, name string) (*appsv1.DaemonSet, error) {
+	return s.workloads.Apps().V1().DaemonSets().Lister().DaemonSets(namespace).Get(name)
+}
+
+func (s *cacheSource) getReplicaSet(namespace, name string) (*appsv1.ReplicaSet, error) {
+	return s.workloads.Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace).Get(name)
+}
+
+func (s *cacheSource) getCronJob(namespace, name string) (*batchv1.CronJob, error) {
+	return s.workloads.Batch().V1().CronJobs().Lister().CronJobs(namespace).Get(name)
+}
+
+func (s *cacheSource) listReplicaSets(namespace string, selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+	return s.pods.Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace).List(selector)
+}
+
+func (s *cacheSource) listPods(namespace string, selector labels.Selector) ([]*corev1.Pod, error) {
+	return s.pods.Core().V1().Pods().Lister().Pods(namespace).List(selector)
+}
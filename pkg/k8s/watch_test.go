@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// watchInBackground runs WatchWorkload for the deployment "web" and returns its result channel.
+// The first progress message signals that the informers have synced.
+func watchInBackground(t *testing.T, client *Client) (<-chan error, <-chan string) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	messages := make(chan string, 16)
+	go func() {
+		done <- client.WatchWorkload(Workload{Kind: KindDeployment, Name: "web"}, 30*time.Second, nil, func(message string) {
+			select {
+			case messages <- message:
+			default:
+			}
+		})
+	}()
+	return done, messages
+}
+
+// waitFor waits for a progress message or the end of the watch
+func waitFor(t *testing.T, done <-chan error, messages <-chan string) string {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		return message
+	case err := <-done:
+		t.Fatalf("watch ended before reporting progress: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no progress reported")
+	}
+	return ""
+}
+
+func TestWatchWorkloadFinishesOnStatusUpdate(t *testing.T) {
+	deployment := testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{Replicas: 1})
+	clientset := fake.NewSimpleClientset(deployment, testReplicaSet())
+	client := &Client{clientset: clientset, namespace: testNamespace}
+
+	done, messages := watchInBackground(t, client)
+	if message := waitFor(t, done, messages); !strings.Contains(message, "0 out of 1 new replicas have been updated") {
+		t.Errorf("progress = %q, want the updated replica count", message)
+	}
+
+	// The controller rolls out the new ReplicaSet
+	deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	if _, err := clientset.AppsV1().Deployments(testNamespace).UpdateStatus(context.Background(), deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WatchWorkload() = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("WatchWorkload() did not finish after the status update")
+	}
+}
+
+func TestWatchWorkloadFailsOnNewPod(t *testing.T) {
+	deployment := testDeployment(ptrTo[int32](1), appsv1.DeploymentStatus{Replicas: 1})
+	clientset := fake.NewSimpleClientset(deployment, testReplicaSet())
+	client := &Client{clientset: clientset, namespace: testNamespace}
+
+	done, messages := watchInBackground(t, client)
+	waitFor(t, done, messages)
+
+	// A pod of the new ReplicaSet can't pull its image
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-abc123-x1",
+			Namespace: testNamespace,
+			UID:       "pod-uid",
+			Labels:    map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "abc123"},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		},
+	}
+	if _, err := clientset.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+			t.Errorf("WatchWorkload() = %v, want an ImagePullBackOff failure", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("WatchWorkload() did not fail after the pod was created")
+	}
+}
@@ -0,0 +1,246 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WorkloadKind identifies a pod-template based workload resource
+type WorkloadKind string
+
+const (
+	KindDeployment  WorkloadKind = "Deployment"
+	KindStatefulSet WorkloadKind = "StatefulSet"
+	KindDaemonSet   WorkloadKind = "DaemonSet"
+	KindReplicaSet  WorkloadKind = "ReplicaSet"
+	KindCronJob     WorkloadKind = "CronJob"
+)
+
+// WorkloadKinds lists all supported workload kinds in display order. Jobs are not among them,
+// as the pod template of a Job is immutable.
+var WorkloadKinds = []WorkloadKind{
+	KindDeployment,
+	KindStatefulSet,
+	KindDaemonSet,
+	KindReplicaSet,
+	KindCronJob,
+}
+
+// Resource returns the kubectl-style "resource.group" name of the kind
+func (k WorkloadKind) Resource() string {
+	switch k {
+	case KindDeployment:
+		return "deployment.apps"
+	case KindStatefulSet:
+		return "statefulset.apps"
+	case KindDaemonSet:
+		return "daemonset.apps"
+	case KindReplicaSet:
+		return "replicaset.apps"
+	case KindCronJob:
+		return "cronjob.batch"
+	}
+	return strings.ToLower(string(k))
+}
+
+// Workload identifies a workload by kind and name.
+// An empty Namespace refers to the client's current namespace.
+type Workload struct {
+	Kind      WorkloadKind
+	Name      string
+	Namespace string
+}
+
+// String returns the workload in kubectl's "resource.group/name" form
+func (w Workload) String() string {
+	return fmt.Sprintf("%s/%s", w.Kind.Resource(), w.Name)
+}
+
+// namespaceOf returns the namespace a workload lives in
+func (c *Client) namespaceOf(w Workload) string {
+	if w.Namespace != "" {
+		return w.Namespace
+	}
+	return c.namespace
+}
+
+// WorkloadInfo represents a workload with a short status summary
+type WorkloadInfo struct {
+	Workload
+	Summary string
+}
+
+// ListWorkloads returns all supported workloads in the current namespace
+func (c *Client) ListWorkloads() ([]WorkloadInfo, error) {
+	ctx := context.Background()
+	var workloads []WorkloadInfo
+
+	deployments, err := c.clientset.AppsV1().Deployments(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		workloads = append(workloads, WorkloadInfo{
+			Workload: Workload{Kind: KindDeployment, Name: d.Name},
+			Summary:  fmt.Sprintf("Replicas: %d, Available: %d", replicasOrDefault(d.Spec.Replicas), d.Status.AvailableReplicas),
+		})
+	}
+
+	statefulSets, err := c.clientset.AppsV1().StatefulSets(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		workloads = append(workloads, WorkloadInfo{
+			Workload: Workload{Kind: KindStatefulSet, Name: s.Name},
+			Summary:  fmt.Sprintf("Replicas: %d, Ready: %d", replicasOrDefault(s.Spec.Replicas), s.Status.ReadyReplicas),
+		})
+	}
+
+	daemonSets, err := c.clientset.AppsV1().DaemonSets(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		workloads = append(workloads, WorkloadInfo{
+			Workload: Workload{Kind: KindDaemonSet, Name: d.Name},
+			Summary:  fmt.Sprintf("Desired: %d, Available: %d", d.Status.DesiredNumberScheduled, d.Status.NumberAvailable),
+		})
+	}
+
+	replicaSets, err := c.clientset.AppsV1().ReplicaSets(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %v", err)
+	}
+	for _, r := range replicaSets.Items {
+		// ReplicaSets managed by a Deployment are updated through their owner
+		if metav1.GetControllerOf(&r) != nil {
+			continue
+		}
+		workloads = append(workloads, WorkloadInfo{
+			Workload: Workload{Kind: KindReplicaSet, Name: r.Name},
+			Summary:  fmt.Sprintf("Replicas: %d, Available: %d", replicasOrDefault(r.Spec.Replicas), r.Status.AvailableReplicas),
+		})
+	}
+
+	cronJobs, err := c.clientset.BatchV1().CronJobs(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %v", err)
+	}
+	for _, cj := range cronJobs.Items {
+		workloads = append(workloads, WorkloadInfo{
+			Workload: Workload{Kind: KindCronJob, Name: cj.Name},
+			Summary:  fmt.Sprintf("Schedule: %s, Active: %d", cj.Spec.Schedule, len(cj.Status.Active)),
+		})
+	}
+
+	return workloads, nil
+}
+
+// getPodTemplate fetches the pod template and pod selector of a workload
+func (c *Client) getPodTemplate(ctx context.Context, w Workload) (*corev1.PodTemplateSpec, *metav1.LabelSelector, error) {
+	switch w.Kind {
+	case KindDeployment:
+		obj, err := c.clientset.AppsV1().Deployments(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		return &obj.Spec.Template, obj.Spec.Selector, nil
+	case KindStatefulSet:
+		obj, err := c.clientset.AppsV1().StatefulSets(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		return &obj.Spec.Template, obj.Spec.Selector, nil
+	case KindDaemonSet:
+		obj, err := c.clientset.AppsV1().DaemonSets(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		return &obj.Spec.Template, obj.Spec.Selector, nil
+	case KindReplicaSet:
+		obj, err := c.clientset.AppsV1().ReplicaSets(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		return &obj.Spec.Template, obj.Spec.Selector, nil
+	case KindCronJob:
+		obj, err := c.clientset.BatchV1().CronJobs(c.namespaceOf(w)).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", w, err)
+		}
+		return &obj.Spec.JobTemplate.Spec.Template, obj.Spec.JobTemplate.Spec.Selector, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported workload kind: %s", w.Kind)
+}
+
+// selectPods lists the pods of a workload. CronJob pods are selected through the Jobs the CronJob
+// owns, as its job template rarely has a selector of its own.
+func (c *Client) selectPods(ctx context.Context, w Workload) ([]*corev1.Pod, error) {
+	src := &apiSource{ctx: ctx, clientset: c.clientset}
+	namespace := c.namespaceOf(w)
+
+	if w.Kind != KindCronJob {
+		_, labelSelector, err := c.getPodTemplate(ctx, w)
+		if err != nil || labelSelector == nil {
+			return nil, err
+		}
+		return listSelectedPods(src, namespace, labelSelector)
+	}
+
+	cronJob, err := c.clientset.BatchV1().CronJobs(namespace).Get(ctx, w.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %v", w, err)
+	}
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs of %s: %v", w, err)
+	}
+
+	var pods []*corev1.Pod
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if owner := metav1.GetControllerOf(job); owner == nil || owner.UID != cronJob.UID || job.Spec.Selector == nil {
+			continue
+		}
+		// The job controller sets a selector on the controller-uid label of each Job
+		jobPods, err := listSelectedPods(src, namespace, job.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, jobPods...)
+	}
+	return pods, nil
+}
+
+// podTemplateOf returns the pod template of a workload object
+func podTemplateOf(obj runtime.Object) (*corev1.PodTemplateSpec, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template, nil
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template, nil
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template, nil
+	case *appsv1.ReplicaSet:
+		return &o.Spec.Template, nil
+	case *batchv1.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template, nil
+	}
+	return nil, fmt.Errorf("unsupported workload object: %T", obj)
+}
+
+// replicasOrDefault dereferences a replica count, defaulting to 1 like the API server
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
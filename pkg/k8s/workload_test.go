@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSelectPodsOfCronJob(t *testing.T) {
+	// testJob returns a Job selecting its pods by controller-uid, as the job controller sets it up
+	testJob := func(name string, owner *batchv1.CronJob) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: types.UID(name + "-uid")},
+			Spec: batchv1.JobSpec{Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{batchv1.ControllerUidLabel: name + "-uid"},
+			}},
+		}
+		if owner != nil {
+			job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+		}
+		return job
+	}
+	testJobPod := func(name, job string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{batchv1.ControllerUidLabel: job + "-uid"},
+		}}
+	}
+
+	// The job template has no selector, like most CronJobs
+	cronJob := &batchv1.CronJob{ObjectMeta: testMeta()}
+	clientset := fake.NewSimpleClientset(cronJob,
+		testJob("web-1", cronJob), testJobPod("web-1-a", "web-1"),
+		testJob("web-2", cronJob), testJobPod("web-2-a", "web-2"),
+		testJob("manual", nil), testJobPod("manual-a", "manual"))
+	client := &Client{clientset: clientset, namespace: testNamespace}
+
+	pods, err := client.selectPods(context.Background(), Workload{Kind: KindCronJob, Name: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, pod := range pods {
+		names[pod.Name] = true
+	}
+	if len(pods) != 2 || !names["web-1-a"] || !names["web-2-a"] {
+		t.Errorf("selectPods() = %v, want the pods of the Jobs owned by the CronJob: web-1-a, web-2-a", names)
+	}
+}
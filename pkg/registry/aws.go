@@ -2,6 +2,8 @@ package registry
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -12,6 +14,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
 )
 
 // AWSProvider handles Amazon ECR registry
@@ -30,7 +35,8 @@ func (p *AWSProvider) Name() string {
 // SupportsImage checks if this provider can handle the given image
 func (p *AWSProvider) SupportsImage(image string) bool {
 	// AWS ECR uses format: <account-id>.dkr.ecr.<region>.amazonaws.com
-	return strings.Contains(image, ".dkr.ecr.") && strings.Contains(image, ".amazonaws.com")
+	ref, err := imageref.Parse(image)
+	return err == nil && ecrHostRegex.MatchString(ref.Registry)
 }
 
 // ListTags fetches available tags for an image
@@ -51,7 +57,7 @@ func (p *AWSProvider) ListTags(image string) ([]string, error) {
 // ListTagsWithInfo fetches available tags with creation time info
 func (p *AWSProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 	// Parse the ECR image URL to extract region and repository
-	region, repository, err := p.parseECRImage(image)
+	region, ref, err := p.parseECRImage(image)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +74,7 @@ func (p *AWSProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 
 	// Call DescribeImages to get tags and timestamps
 	input := &ecr.DescribeImagesInput{
-		RepositoryName: aws.String(repository),
+		RepositoryName: aws.String(ref.Repository),
 		MaxResults:     aws.Int32(100), // Limit to 100 images for performance
 	}
 
@@ -79,7 +85,7 @@ func (p *AWSProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 	for paginator.HasMorePages() {
 		result, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to describe images for repository %s: %v", repository, err)
+			return nil, fmt.Errorf("failed to describe images for repository %s: %v", ref.Repository, err)
 		}
 		allImageDetails = append(allImageDetails, result.ImageDetails...)
 
@@ -107,15 +113,22 @@ func (p *AWSProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 				createdAt = *imageDetail.ImagePushedAt
 			}
 
-			tagInfos = append(tagInfos, TagInfo{
+			tagInfo := TagInfo{
 				Tag:       tag,
 				CreatedAt: createdAt,
-			})
+			}
+			if imageDetail.ImageDigest != nil {
+				tagInfo.Digest = *imageDetail.ImageDigest
+			}
+			if imageDetail.ImageSizeInBytes != nil {
+				tagInfo.Size = *imageDetail.ImageSizeInBytes
+			}
+			tagInfos = append(tagInfos, tagInfo)
 		}
 	}
 
 	if len(tagInfos) == 0 {
-		return nil, fmt.Errorf("no tagged images found in ECR repository %s", repository)
+		return nil, fmt.Errorf("no tagged images found in ECR repository %s", ref.Repository)
 	}
 
 	// Sort by creation time (newest first)
@@ -131,33 +144,139 @@ func (p *AWSProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 	return tagInfos, nil
 }
 
-// parseECRImage parses an ECR image URL and extracts the region and repository name
-func (p *AWSProvider) parseECRImage(image string) (region, repository string, err error) {
-	// ECR image format: <account-id>.dkr.ecr.<region>.amazonaws.com/<repository>:<tag>
-	// Example: 123456789012.dkr.ecr.us-west-2.amazonaws.com/my-repo:latest
+// ResolveDigest returns the manifest digest an image reference points to
+func (p *AWSProvider) ResolveDigest(image string) (string, error) {
+	region, ref, err := p.parseECRImage(image)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS config: %v", err)
+	}
+
+	svc := ecr.NewFromConfig(cfg)
+	result, err := svc.DescribeImages(ctx, &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(ref.Repository),
+		ImageIds:       []types.ImageIdentifier{{ImageTag: aws.String(ecrTag(ref))}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe image %s: %v", image, err)
+	}
+	if len(result.ImageDetails) == 0 || result.ImageDetails[0].ImageDigest == nil {
+		return "", fmt.Errorf("image %s not found in ECR repository %s", image, ref.Repository)
+	}
+
+	return *result.ImageDetails[0].ImageDigest, nil
+}
+
+// VerifyImage checks that an image exists and can be pulled, using the same BatchGetImage call
+// (and IAM permission) as a pull
+func (p *AWSProvider) VerifyImage(image string) error {
+	region, ref, err := p.parseECRImage(image)
+	if err != nil {
+		return err
+	}
+
+	imageID := types.ImageIdentifier{ImageTag: aws.String(ecrTag(ref))}
+	if ref.Digest != "" {
+		imageID = types.ImageIdentifier{ImageDigest: aws.String(ref.Digest)}
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %v", err)
+	}
+
+	svc := ecr.NewFromConfig(cfg)
+	result, err := svc.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RepositoryName: aws.String(ref.Repository),
+		ImageIds:       []types.ImageIdentifier{imageID},
+	})
+	if err != nil {
+		var notFound *types.RepositoryNotFoundException
+		if errors.As(err, &notFound) {
+			return &NotFoundError{Image: image}
+		}
+		return fmt.Errorf("failed to pull %s from ECR: %v", image, err)
+	}
+	if len(result.Images) == 0 {
+		return &NotFoundError{Image: image}
+	}
+
+	return nil
+}
+
+// ListTagNames returns all tags of the image's ECR repository
+func (p *AWSProvider) ListTagNames(image string) ([]string, error) {
+	region, ref, err := p.parseECRImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+
+	var tags []string
+	paginator := ecr.NewListImagesPaginator(ecr.NewFromConfig(cfg), &ecr.ListImagesInput{
+		RepositoryName: aws.String(ref.Repository),
+		Filter:         &types.ListImagesFilter{TagStatus: types.TagStatusTagged},
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images for repository %s: %v", ref.Repository, err)
+		}
+		for _, imageID := range result.ImageIds {
+			if imageID.ImageTag != nil {
+				tags = append(tags, *imageID.ImageTag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// ImagePlatforms returns the platforms an image reference can run on
+func (p *AWSProvider) ImagePlatforms(image string) ([]Platform, error) {
+	region, ref, err := p.parseECRImage(image)
+	if err != nil {
+		return nil, err
+	}
 
-	// Regular expression to match ECR URLs
-	ecrRegex := regexp.MustCompile(`^(\d+)\.dkr\.ecr\.([^.]+)\.amazonaws\.com\/([^:]+)(?::(.+))?$`)
+	return imagePlatforms(ref, &ecrKeychain{region: region})
+}
 
-	matches := ecrRegex.FindStringSubmatch(image)
-	if len(matches) < 4 {
-		return "", "", fmt.Errorf("invalid ECR image format: %s. Expected format: <account-id>.dkr.ecr.<region>.amazonaws.com/<repository>[:tag]", image)
+// ecrTag returns the tag ECR looks an image up by, "latest" when the reference has none
+func ecrTag(ref imageref.Reference) string {
+	if ref.Tag == "" {
+		return "latest"
 	}
+	return ref.Tag
+}
 
-	// Extract components
-	// matches[1] = account-id (not needed for our purposes)
-	// matches[2] = region
-	// matches[3] = repository
-	// matches[4] = tag (optional)
+// ecrHostRegex matches ECR registry hosts: <account-id>.dkr.ecr.<region>.amazonaws.com[.cn]
+var ecrHostRegex = regexp.MustCompile(`^(\d+)\.dkr\.ecr\.([^.]+)\.amazonaws\.com(\.cn)?$`)
 
-	region = matches[2]
-	repository = matches[3]
+// parseECRImage parses an ECR image reference and extracts the region from its registry host
+func (p *AWSProvider) parseECRImage(image string) (string, imageref.Reference, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", imageref.Reference{}, err
+	}
 
-	if region == "" || repository == "" {
-		return "", "", fmt.Errorf("failed to extract region or repository from ECR image: %s", image)
+	// Example: 123456789012.dkr.ecr.us-west-2.amazonaws.com/my-repo:latest
+	matches := ecrHostRegex.FindStringSubmatch(ref.Registry)
+	if matches == nil {
+		return "", imageref.Reference{}, fmt.Errorf("invalid ECR image format: %s. Expected format: <account-id>.dkr.ecr.<region>.amazonaws.com/<repository>[:tag]", image)
 	}
 
-	return region, repository, nil
+	return matches[2], ref, nil
 }
 
 // validateECRAccess validates that we can access the ECR registry
@@ -181,3 +300,36 @@ func (p *AWSProvider) validateECRAccess(region, repository string) error {
 
 	return nil
 }
+
+// ecrKeychain implements authn.Keychain with an ECR authorization token
+type ecrKeychain struct {
+	region string
+}
+
+func (k *ecrKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(k.region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+
+	result, err := ecr.NewFromConfig(cfg).GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ECR authorization token: %v", err)
+	}
+	if len(result.AuthorizationData) == 0 || result.AuthorizationData[0].AuthorizationToken == nil {
+		return nil, fmt.Errorf("no ECR authorization token returned")
+	}
+
+	// The token is base64 of "AWS:password"
+	decoded, err := base64.StdEncoding.DecodeString(*result.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ECR authorization token: %v", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed ECR authorization token")
+	}
+
+	return &authn.Basic{Username: username, Password: password}, nil
+}
@@ -0,0 +1,386 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+)
+
+// acrHostRegex matches ACR registry hosts in the public and sovereign clouds
+var acrHostRegex = regexp.MustCompile(`^[a-z0-9]+\.azurecr\.(io|cn|us)$`)
+
+// acrScope is the AAD scope of tokens accepted by the ACR token exchange
+const acrScope = "https://containerregistry.azure.net/.default"
+
+// acrUsername is the username ACR expects with a refresh token as password
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// AzureProvider handles Azure Container Registry
+type AzureProvider struct {
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	credential    azcore.TokenCredential
+	refreshTokens map[string]string
+	refreshErrors map[string]error
+}
+
+// NewAzureProvider creates a new Azure ACR registry provider
+func NewAzureProvider() *AzureProvider {
+	return &AzureProvider{
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		refreshTokens: make(map[string]string),
+		refreshErrors: make(map[string]error),
+	}
+}
+
+// Name returns the provider name
+func (p *AzureProvider) Name() string {
+	return "Azure ACR"
+}
+
+// SupportsImage checks if this provider can handle the given image
+func (p *AzureProvider) SupportsImage(image string) bool {
+	// ACR uses format: <registry>.azurecr.io
+	ref, err := imageref.Parse(image)
+	return err == nil && acrHostRegex.MatchString(ref.Registry)
+}
+
+// ListTags fetches available tags for an image
+func (p *AzureProvider) ListTags(image string) ([]string, error) {
+	tagInfos, err := p.ListTagsWithInfo(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, len(tagInfos))
+	for i, tagInfo := range tagInfos {
+		tags[i] = tagInfo.Tag
+	}
+
+	return tags, nil
+}
+
+// acrManifest is an entry of the ACR manifest listing API
+type acrManifest struct {
+	Digest         string    `json:"digest"`
+	ImageSize      int64     `json:"imageSize"`
+	CreatedTime    time.Time `json:"createdTime"`
+	LastUpdateTime time.Time `json:"lastUpdateTime"`
+	Architecture   string    `json:"architecture"`
+	OS             string    `json:"os"`
+	Tags           []string  `json:"tags"`
+
+	// References are the platform manifests of a multi-arch index
+	References []struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"references"`
+}
+
+// ListTagsWithInfo fetches available tags with creation time info. The ACR manifest listing API
+// returns timestamps, digests, sizes and platforms for a page of manifests in one call.
+func (p *AzureProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.accessToken(ref.Registry, fmt.Sprintf("repository:%s:metadata_read", ref.Repository))
+	if err != nil {
+		// Without Azure AD credentials, users of az acr login or the admin user still get tags
+		// through the registry API with their docker config credentials
+		tagInfos, listErr := p.listRegistryTags(ref)
+		if listErr != nil {
+			return nil, fmt.Errorf("%v; %v", err, listErr)
+		}
+		return tagInfos, nil
+	}
+
+	var tagInfos []TagInfo
+	next := fmt.Sprintf("https://%s/acr/v1/%s/_manifests?orderby=timedesc&n=100", ref.Registry, ref.Repository)
+	// Manifests come newest first, so a page or two holds the most recent tags
+	for next != "" && len(tagInfos) < 20 {
+		var page struct {
+			Manifests []acrManifest `json:"manifests"`
+		}
+		next, err = p.getJSON(next, token, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list manifests for %s: %v", ref.FullName(), err)
+		}
+
+		for _, manifest := range page.Manifests {
+			var architectures []string
+			if manifest.Architecture != "" {
+				architectures = append(architectures, manifest.OS+"/"+manifest.Architecture)
+			}
+			for _, reference := range manifest.References {
+				if reference.Architecture != "" && reference.OS != "unknown" {
+					architectures = append(architectures, reference.OS+"/"+reference.Architecture)
+				}
+			}
+
+			createdAt := manifest.CreatedTime
+			if createdAt.IsZero() {
+				createdAt = manifest.LastUpdateTime
+			}
+
+			for _, tag := range manifest.Tags {
+				tagInfos = append(tagInfos, TagInfo{
+					Tag:           tag,
+					CreatedAt:     createdAt,
+					Digest:        manifest.Digest,
+					Size:          manifest.ImageSize,
+					Architectures: architectures,
+				})
+			}
+		}
+	}
+
+	if len(tagInfos) == 0 {
+		return nil, fmt.Errorf("no tagged images found in ACR repository %s", ref.FullName())
+	}
+
+	// Sort by creation time (newest first)
+	sort.Slice(tagInfos, func(i, j int) bool {
+		return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
+	})
+
+	// Limit to 20 tags for performance
+	if len(tagInfos) > 20 {
+		tagInfos = tagInfos[:20]
+	}
+
+	return tagInfos, nil
+}
+
+// listRegistryTags lists tags through the registry API and reads the creation time of each from its config
+func (p *AzureProvider) listRegistryTags(ref imageref.Reference) ([]TagInfo, error) {
+	repo := ref.Context()
+
+	keychain := &acrKeychain{provider: p}
+
+	tags, err := remote.List(repo, remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", repo.String(), err)
+	}
+
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags found for image %s", repo.String())
+	}
+
+	return tagsWithCreationTime(repo, tags, keychain), nil
+}
+
+// ResolveDigest returns the manifest digest an image reference points to
+func (p *AzureProvider) ResolveDigest(image string) (string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", err
+	}
+
+	return headDigest(ref, &acrKeychain{provider: p})
+}
+
+// VerifyImage checks that an image reference exists and can be pulled
+func (p *AzureProvider) VerifyImage(image string) error {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return err
+	}
+
+	return verifyRemote(ref, &acrKeychain{provider: p})
+}
+
+// ImagePlatforms returns the platforms an image reference can run on
+func (p *AzureProvider) ImagePlatforms(image string) ([]Platform, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	return imagePlatforms(ref, &acrKeychain{provider: p})
+}
+
+// ListTagNames returns all tags of the image's repository
+func (p *AzureProvider) ListTagNames(image string) ([]string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := remote.List(ref.Context(), remote.WithAuthFromKeychain(&acrKeychain{provider: p}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", ref.Context(), err)
+	}
+	return tags, nil
+}
+
+// tokenCredential returns the AAD credential, trying environment variables (service principal),
+// workload identity and the az CLI login in that order
+func (p *AzureProvider) tokenCredential() (azcore.TokenCredential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.credential != nil {
+		return p.credential, nil
+	}
+
+	var sources []azcore.TokenCredential
+	// Environment and workload identity credentials fail to build when their variables are unset
+	if credential, err := azidentity.NewEnvironmentCredential(nil); err == nil {
+		sources = append(sources, credential)
+	}
+	if credential, err := azidentity.NewWorkloadIdentityCredential(nil); err == nil {
+		sources = append(sources, credential)
+	}
+	if credential, err := azidentity.NewAzureCLICredential(nil); err == nil {
+		sources = append(sources, credential)
+	}
+
+	credential, err := azidentity.NewChainedTokenCredential(sources, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credentials: %v", err)
+	}
+	p.credential = credential
+	return credential, nil
+}
+
+// refreshToken returns an ACR refresh token for a registry. Tokens and failures are cached, as
+// getting an AAD token can mean running the az CLI.
+func (p *AzureProvider) refreshToken(registry string) (string, error) {
+	p.mu.Lock()
+	token, ok := p.refreshTokens[registry]
+	err := p.refreshErrors[registry]
+	p.mu.Unlock()
+	if ok || err != nil {
+		return token, err
+	}
+
+	token, err = p.exchangeToken(registry)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.refreshErrors[registry] = err
+		return "", err
+	}
+	p.refreshTokens[registry] = token
+	return token, nil
+}
+
+// exchangeToken exchanges an AAD access token for an ACR refresh token
+func (p *AzureProvider) exchangeToken(registry string) (string, error) {
+	credential, err := p.tokenCredential()
+	if err != nil {
+		return "", err
+	}
+
+	aadToken, err := credential.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{acrScope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Azure AD token (run az login or set AZURE_* environment variables): %v", err)
+	}
+
+	var result struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	err = p.postForm(fmt.Sprintf("https://%s/oauth2/exchange", registry), url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registry},
+		"access_token": {aadToken.Token},
+	}, &result)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange Azure AD token for an ACR refresh token: %v", err)
+	}
+	return result.RefreshToken, nil
+}
+
+// accessToken returns an ACR access token for a scope such as "repository:app:metadata_read"
+func (p *AzureProvider) accessToken(registry, scope string) (string, error) {
+	refreshToken, err := p.refreshToken(registry)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = p.postForm(fmt.Sprintf("https://%s/oauth2/token", registry), url.Values{
+		"grant_type":    {"refresh_token"},
+		"service":       {registry},
+		"scope":         {scope},
+		"refresh_token": {refreshToken},
+	}, &result)
+	if err != nil {
+		return "", fmt.Errorf("failed to get ACR access token: %v", err)
+	}
+	return result.AccessToken, nil
+}
+
+// postForm posts a form to an ACR token endpoint and decodes the JSON response
+func (p *AzureProvider) postForm(endpoint string, form url.Values, result interface{}) error {
+	resp, err := p.httpClient.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// getJSON fetches an ACR API page with a bearer token and returns the URL of the next page
+// from the Link header, or "" on the last page
+func (p *AzureProvider) getJSON(endpoint, token string, result interface{}) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", err
+	}
+
+	return nextLink(resp, endpoint), nil
+}
+
+// acrKeychain implements authn.Keychain with an ACR refresh token, falling back to the docker
+// config (e.g. after az acr login) when no Azure AD credentials are available
+type acrKeychain struct {
+	provider *AzureProvider
+}
+
+func (k *acrKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	refreshToken, err := k.provider.refreshToken(resource.RegistryStr())
+	if err != nil {
+		return authn.DefaultKeychain.Resolve(resource)
+	}
+
+	return &authn.Basic{Username: acrUsername, Password: refreshToken}, nil
+}
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// stubCredential returns a fixed Azure AD token or error
+type stubCredential struct {
+	token string
+	err   error
+}
+
+func (c *stubCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	if c.err != nil {
+		return azcore.AccessToken{}, c.err
+	}
+	return azcore.AccessToken{Token: c.token, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// newACRAPI starts a stub of the ACR token and metadata APIs and returns a provider sending
+// requests for any registry host to it
+func newACRAPI(t *testing.T, credential azcore.TokenCredential, handler http.HandlerFunc) *AzureProvider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := NewAzureProvider()
+	p.credential = credential
+	p.httpClient = &http.Client{Transport: &registryRedirect{
+		host: strings.TrimPrefix(server.URL, "http://"),
+		next: http.DefaultTransport,
+	}}
+	return p
+}
+
+func TestAzureProviderListTagsWithInfo(t *testing.T) {
+	p := newACRAPI(t, &stubCredential{token: "aad-token"}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/exchange":
+			if r.FormValue("access_token") != "aad-token" || r.FormValue("service") != "example.azurecr.io" {
+				t.Errorf("exchange form = %v, want the Azure AD token for example.azurecr.io", r.PostForm)
+			}
+			fmt.Fprint(w, `{"refresh_token": "refresh-token"}`)
+		case "/oauth2/token":
+			if r.FormValue("refresh_token") != "refresh-token" || r.FormValue("scope") != "repository:team/app:metadata_read" {
+				t.Errorf("token form = %v, want the refresh token and a metadata_read scope", r.PostForm)
+			}
+			fmt.Fprint(w, `{"access_token": "access-token"}`)
+		case "/acr/v1/team/app/_manifests":
+			if got := r.Header.Get("Authorization"); got != "Bearer access-token" {
+				t.Errorf("Authorization = %q, want the ACR access token", got)
+			}
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</acr/v1/team/app/_manifests?orderby=timedesc&n=100&last=sha256:index>; rel="next"`)
+				fmt.Fprint(w, `{"manifests": [
+					{"digest": "sha256:index", "imageSize": 100, "createdTime": "2024-03-01T00:00:00Z", "tags": ["v2", "latest"], "references": [
+						{"architecture": "amd64", "os": "linux"},
+						{"architecture": "unknown", "os": "unknown"}
+					]}
+				]}`)
+				return
+			}
+			fmt.Fprint(w, `{"manifests": [
+				{"digest": "sha256:single", "imageSize": 90, "lastUpdateTime": "2024-02-01T00:00:00Z", "architecture": "arm64", "os": "linux", "tags": ["v1"]}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("example.azurecr.io/team/app:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tagInfos) != 3 {
+		t.Fatalf("ListTagsWithInfo() returned %d tags from two pages, want 3: %+v", len(tagInfos), tagInfos)
+	}
+
+	var got []string
+	for _, tagInfo := range tagInfos {
+		got = append(got, fmt.Sprintf("%s %s %s", tagInfo.Tag, tagInfo.Digest, strings.Join(tagInfo.Architectures, ",")))
+	}
+	// Tags of the same manifest share a creation time, so only the last one has a fixed place
+	sort.Strings(got[:2])
+	want := []string{
+		"latest sha256:index linux/amd64",
+		"v2 sha256:index linux/amd64",
+		"v1 sha256:single linux/arm64",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ListTagsWithInfo() from two pages =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !tagInfos[2].CreatedAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("v1 created at %v, want its lastUpdateTime without a createdTime", tagInfos[2].CreatedAt)
+	}
+}
+
+func TestAzureProviderFallsBackToRegistry(t *testing.T) {
+	host, _ := newTestRegistry(t)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	digest := pushImage(t, host+"/team/app:v1", created)
+
+	// example.azurecr.io is served by the test registry
+	transport := remote.DefaultTransport
+	remote.DefaultTransport = &registryRedirect{host: host, next: transport}
+	t.Cleanup(func() { remote.DefaultTransport = transport })
+
+	// Without Azure AD credentials the ACR API must not be called
+	p := newACRAPI(t, &stubCredential{err: fmt.Errorf("no credentials")}, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected ACR API request %s", r.URL)
+		http.Error(w, "unexpected", http.StatusInternalServerError)
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("example.azurecr.io/team/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagInfos) != 1 || tagInfos[0].Tag != "v1" || tagInfos[0].Digest != digest.String() {
+		t.Fatalf("ListTagsWithInfo() = %+v, want v1 from the registry", tagInfos)
+	}
+	if !tagInfos[0].CreatedAt.Equal(created) {
+		t.Errorf("v1 created at %v, want %v from its config", tagInfos[0].CreatedAt, created)
+	}
+}
@@ -1,23 +1,37 @@
 package registry
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
 )
 
+// dockerHubAPI is the base URL of the Docker Hub API
+const dockerHubAPI = "https://hub.docker.com"
+
 // DockerHubProvider handles Docker Hub registry
-type DockerHubProvider struct{}
+type DockerHubProvider struct {
+	// apiURL is the base URL of the Docker Hub API, without a trailing slash
+	apiURL     string
+	httpClient *http.Client
+}
 
 // NewDockerHubProvider creates a new Docker Hub registry provider
 func NewDockerHubProvider() *DockerHubProvider {
-	return &DockerHubProvider{}
+	return &DockerHubProvider{
+		apiURL:     dockerHubAPI,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
 }
 
 // Name returns the provider name
@@ -27,19 +41,10 @@ func (p *DockerHubProvider) Name() string {
 
 // SupportsImage checks if this provider can handle the given image
 func (p *DockerHubProvider) SupportsImage(image string) bool {
-	// Parse the image to get the registry
-	ref, err := name.ParseReference(image)
-	if err != nil {
-		return false
-	}
-
-	registryHost := ref.Context().Registry.Name()
-	// Docker Hub uses "index.docker.io" or "docker.io" or no registry (default)
-	return registryHost == "index.docker.io" ||
-		registryHost == "docker.io" ||
-		registryHost == name.DefaultRegistry ||
-		!strings.Contains(image, "/") || // Simple image name like "nginx"
-		(strings.Count(image, "/") == 1 && !strings.Contains(image, ".")) // Format like "library/nginx"
+	// "nginx", "library/nginx" and "docker.io/nginx" all resolve to index.docker.io, while a first
+	// component with a dot, a port or "localhost" is a registry host
+	ref, err := imageref.Parse(image)
+	return err == nil && ref.IsDockerHub()
 }
 
 // ListTags fetches available tags for an image
@@ -57,17 +62,47 @@ func (p *DockerHubProvider) ListTags(image string) ([]string, error) {
 	return tags, nil
 }
 
-// ListTagsWithInfo fetches available tags with creation time info
+// ListTagsWithInfo fetches available tags with push time info from the Docker Hub API, falling
+// back to the registry and image configs when the API can't see the repository, e.g. a private one.
+// Other failures are returned, as the fallback pulls a config per tag and would make rate limiting
+// worse.
 func (p *DockerHubProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
-	repo, err := name.NewRepository(image)
+	ref, err := imageref.Parse(image)
 	if err != nil {
-		// If parsing as repository fails, try to extract repository from full image
-		ref, err := name.ParseReference(image)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse image reference %s: %v", image, err)
-		}
-		repo = ref.Context()
+		return nil, err
+	}
+
+	tagInfos, err := p.listHubTags(ref)
+	var statusErr *hubStatusError
+	if errors.As(err, &statusErr) && statusErr.hidden() {
+		return p.listRegistryTags(ref)
+	}
+	return tagInfos, err
+}
+
+// hubStatusError is a non-200 response of the Docker Hub API
+type hubStatusError struct {
+	endpoint string
+	status   string
+	code     int
+}
+
+func (e *hubStatusError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.endpoint, e.status)
+}
+
+// hidden reports whether the repository is private or unknown to the anonymous API
+func (e *hubStatusError) hidden() bool {
+	switch e.code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
 	}
+	return false
+}
+
+// listRegistryTags lists tags through the registry API and reads the creation time of each from its config
+func (p *DockerHubProvider) listRegistryTags(ref imageref.Reference) ([]TagInfo, error) {
+	repo := ref.Context()
 
 	keychain := authn.DefaultKeychain
 
@@ -80,102 +115,104 @@ func (p *DockerHubProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
 		return nil, fmt.Errorf("no tags found for image %s", repo.String())
 	}
 
-	tagInfos, err := p.getTagsWithCreationTime(repo, tags, keychain)
-	if err != nil {
-		// If we can't get creation times, fall back to alphabetical sort and create TagInfo with zero time
-		sort.Strings(tags)
-		tagInfos = make([]TagInfo, len(tags))
-		for i, tag := range tags {
-			tagInfos[i] = TagInfo{
-				Tag:       tag,
-				CreatedAt: time.Time{}, // Zero time indicates no timestamp available
-			}
-		}
-	} else {
-		// Sort by creation time (newest first)
-		sort.Slice(tagInfos, func(i, j int) bool {
-			return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
-		})
-	}
-
-	// Limit to 20 tags for performance
-	if len(tagInfos) > 20 {
-		tagInfos = tagInfos[:20]
-	}
-
-	return tagInfos, nil
+	return tagsWithCreationTime(repo, tags, keychain), nil
 }
 
-// getTagsWithCreationTime fetches creation time for each tag
-func (p *DockerHubProvider) getTagsWithCreationTime(repo name.Repository, tags []string, keychain authn.Keychain) ([]TagInfo, error) {
-	var tagInfos []TagInfo
-
-	maxConcurrent := 10
-	if len(tags) < maxConcurrent {
-		maxConcurrent = len(tags)
-	}
+// hubTag is a tag of the Docker Hub tags API
+type hubTag struct {
+	Name          string    `json:"name"`
+	LastUpdated   time.Time `json:"last_updated"`
+	TagLastPushed time.Time `json:"tag_last_pushed"`
+	Digest        string    `json:"digest"`
+	FullSize      int64     `json:"full_size"`
+	Images        []struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+		Digest       string `json:"digest"`
+		Size         int64  `json:"size"`
+	} `json:"images"`
+}
 
-	tagsToProcess := tags
-	if len(tags) > 50 {
-		tagsToProcess = tags[:50] // Limit to first 50 tags for performance
+// listHubTags fetches the most recently updated tags with digests, sizes and platforms from
+// /v2/namespaces/{namespace}/repositories/{repository}/tags, without pulling any manifest
+func (p *DockerHubProvider) listHubTags(ref imageref.Reference) ([]TagInfo, error) {
+	namespace, repository, ok := strings.Cut(ref.Repository, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid Docker Hub repository %s", ref.Repository)
 	}
 
-	results := make(chan TagInfo, len(tagsToProcess))
-	errors := make(chan error, len(tagsToProcess))
-
-	sem := make(chan struct{}, maxConcurrent)
+	next := fmt.Sprintf("%s/v2/namespaces/%s/repositories/%s/tags?page_size=100&ordering=last_updated",
+		p.apiURL, url.PathEscape(namespace), url.PathEscape(repository))
 
-	for _, tag := range tagsToProcess {
-		go func(tag string) {
-			sem <- struct{}{}        // Acquire semaphore
-			defer func() { <-sem }() // Release semaphore
-
-			tagRef, err := name.ParseReference(fmt.Sprintf("%s:%s", repo.String(), tag))
-			if err != nil {
-				errors <- fmt.Errorf("failed to parse tag %s: %v", tag, err)
-				return
-			}
-
-			// Get image manifest
-			img, err := remote.Image(tagRef, remote.WithAuthFromKeychain(keychain))
-			if err != nil {
-				errors <- fmt.Errorf("failed to get image for tag %s: %v", tag, err)
-				return
-			}
-
-			// Get config to extract creation time
-			config, err := img.ConfigFile()
-			if err != nil {
-				errors <- fmt.Errorf("failed to get config for tag %s: %v", tag, err)
-				return
-			}
+	var tagInfos []TagInfo
+	// Tags come most recently updated first, so the first page holds the newest tags
+	for next != "" && len(tagInfos) < 20 {
+		var page struct {
+			Next    string   `json:"next"`
+			Results []hubTag `json:"results"`
+		}
+		if err := p.getJSON(next, &page); err != nil {
+			return nil, err
+		}
+		next = page.Next
 
-			createdAt := config.Created.Time
+		for _, tag := range page.Results {
+			createdAt := tag.LastUpdated
 			if createdAt.IsZero() {
-				// If creation time is not available, use a default old time
-				createdAt = time.Unix(0, 0)
+				createdAt = tag.TagLastPushed
 			}
 
-			results <- TagInfo{
-				Tag:       tag,
+			tagInfo := TagInfo{
+				Tag:       tag.Name,
 				CreatedAt: createdAt,
+				Digest:    tag.Digest,
+				Size:      tag.FullSize,
+			}
+			for _, image := range tag.Images {
+				// Attestation manifests are listed as unknown/unknown
+				if image.OS == "" || image.OS == "unknown" {
+					continue
+				}
+				platform := Platform{OS: image.OS, Architecture: image.Architecture, Variant: image.Variant}
+				tagInfo.Architectures = append(tagInfo.Architectures, platform.String())
+
+				// Tags pushed as a single image have no index digest of their own
+				if tagInfo.Digest == "" && len(tag.Images) == 1 {
+					tagInfo.Digest = image.Digest
+				}
 			}
-		}(tag)
-	}
-
-	for i := 0; i < len(tagsToProcess); i++ {
-		select {
-		case tagInfo := <-results:
 			tagInfos = append(tagInfos, tagInfo)
-		case err := <-errors:
-			// Log error but continue with other tags
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
 	}
 
 	if len(tagInfos) == 0 {
-		return nil, fmt.Errorf("failed to get creation time for any tags")
+		return nil, fmt.Errorf("no tags found for image %s", ref.FullName())
+	}
+
+	// Sort by push time (newest first)
+	sort.Slice(tagInfos, func(i, j int) bool {
+		return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
+	})
+
+	// Limit to 20 tags for performance
+	if len(tagInfos) > 20 {
+		tagInfos = tagInfos[:20]
 	}
 
 	return tagInfos, nil
 }
+
+// getJSON fetches a Docker Hub API page anonymously
+func (p *DockerHubProvider) getJSON(endpoint string, result interface{}) error {
+	resp, err := p.httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &hubStatusError{endpoint: endpoint, status: resp.Status, code: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
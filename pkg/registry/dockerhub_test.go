@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// newHubAPI starts a stub of the Docker Hub API and returns a provider using it
+func newHubAPI(t *testing.T, handler http.HandlerFunc) *DockerHubProvider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := NewDockerHubProvider()
+	p.apiURL = server.URL
+	return p
+}
+
+func TestDockerHubProviderListTagsWithInfo(t *testing.T) {
+	p := newHubAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/namespaces/library/repositories/nginx/tags" {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"next": "http://%s/v2/namespaces/library/repositories/nginx/tags?page=2", "results": [
+				{"name": "1.27", "last_updated": "2024-03-01T00:00:00Z", "digest": "sha256:index", "full_size": 100, "images": [
+					{"architecture": "amd64", "os": "linux", "digest": "sha256:amd64"},
+					{"architecture": "arm64", "os": "linux", "variant": "v8", "digest": "sha256:arm64"},
+					{"architecture": "unknown", "os": "unknown", "digest": "sha256:attestation"}
+				]}
+			]}`, r.Host)
+		case "2":
+			fmt.Fprint(w, `{"next": null, "results": [
+				{"name": "1.26", "last_updated": null, "tag_last_pushed": "2024-02-01T00:00:00Z", "full_size": 90, "images": [
+					{"architecture": "amd64", "os": "linux", "digest": "sha256:single"}
+				]}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("nginx:1.27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagInfos) != 2 {
+		t.Fatalf("ListTagsWithInfo() returned %d tags from two pages, want 2: %+v", len(tagInfos), tagInfos)
+	}
+
+	latest, older := tagInfos[0], tagInfos[1]
+	if latest.Tag != "1.27" || latest.Digest != "sha256:index" || latest.Size != 100 {
+		t.Errorf("first tag = %+v, want 1.27 with the index digest", latest)
+	}
+	if got := strings.Join(latest.Architectures, ","); got != "linux/amd64,linux/arm64/v8" {
+		t.Errorf("architectures of 1.27 = %s, want linux/amd64,linux/arm64/v8 without unknown/unknown", got)
+	}
+
+	if older.Tag != "1.26" || !older.CreatedAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("second tag = %s created at %v, want 1.26 at its tag_last_pushed time", older.Tag, older.CreatedAt)
+	}
+	if older.Digest != "sha256:single" {
+		t.Errorf("digest of 1.26 = %s, want the digest of its only image", older.Digest)
+	}
+}
+
+func TestDockerHubProviderRateLimited(t *testing.T) {
+	// Any registry request would fail the test
+	transport := remote.DefaultTransport
+	remote.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected registry request %s", req.URL)
+		return nil, fmt.Errorf("unexpected registry request")
+	})
+	t.Cleanup(func() { remote.DefaultTransport = transport })
+
+	p := newHubAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	})
+
+	_, err := p.ListTagsWithInfo("nginx:1.27")
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("ListTagsWithInfo() = %v, want the 429 without falling back to the registry", err)
+	}
+}
+
+// roundTripFunc implements http.RoundTripper with a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// registryRedirect sends registry requests for any host to a test registry
+type registryRedirect struct {
+	host string
+	next http.RoundTripper
+}
+
+func (r *registryRedirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = r.host
+	req.Host = r.host
+	return r.next.RoundTrip(req)
+}
+
+func TestDockerHubProviderFallsBackToRegistry(t *testing.T) {
+	host, _ := newTestRegistry(t)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	digest := pushImage(t, host+"/team/private:v1", created)
+
+	// index.docker.io is served by the test registry
+	transport := remote.DefaultTransport
+	remote.DefaultTransport = &registryRedirect{host: host, next: transport}
+	t.Cleanup(func() { remote.DefaultTransport = transport })
+
+	// Private repositories aren't visible to the anonymous API
+	p := newHubAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "object not found"}`, http.StatusNotFound)
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("team/private:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagInfos) != 1 || tagInfos[0].Tag != "v1" || tagInfos[0].Digest != digest.String() {
+		t.Fatalf("ListTagsWithInfo() = %+v, want v1 from the registry", tagInfos)
+	}
+	if !tagInfos[0].CreatedAt.Equal(created) {
+		t.Errorf("v1 created at %v, want %v from its config", tagInfos[0].CreatedAt, created)
+	}
+}
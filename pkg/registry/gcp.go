@@ -10,9 +10,13 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	oauth2google "golang.org/x/oauth2/google"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
 )
 
 // GCPProvider handles GCR and Artifact Registry
@@ -30,14 +34,13 @@ func (p *GCPProvider) Name() string {
 
 // SupportsImage checks if this provider can handle the given image
 func (p *GCPProvider) SupportsImage(image string) bool {
-	// Parse the image to get the registry
-	ref, err := name.ParseReference(image)
+	// GCR hosts are gcr.io and <region>.gcr.io, Artifact Registry hosts <region>-docker.pkg.dev
+	ref, err := imageref.Parse(image)
 	if err != nil {
 		return false
 	}
 
-	registryHost := ref.Context().Registry.Name()
-	return strings.Contains(registryHost, "gcr.io") || strings.Contains(registryHost, "pkg.dev")
+	return ref.Registry == "gcr.io" || strings.HasSuffix(ref.Registry, ".gcr.io") || strings.HasSuffix(ref.Registry, ".pkg.dev")
 }
 
 // ListTags fetches available tags for an image
@@ -47,158 +50,221 @@ func (p *GCPProvider) ListTags(image string) ([]string, error) {
 		return nil, err
 	}
 
-	tags := make([]string, len(tagInfos))
-	for i, tagInfo := range tagInfos {
-		tags[i] = tagInfo.Tag
+	var tags []string
+	for _, tagInfo := range tagInfos {
+		// Untagged manifests are only listed for the tag picker
+		if tagInfo.Tag != "" {
+			tags = append(tags, tagInfo.Tag)
+		}
 	}
 
 	return tags, nil
 }
 
-// ListTagsWithInfo fetches available tags with creation time info
+// ListTagsWithInfo fetches available tags and untagged manifests with creation time info. GCR and
+// Artifact Registry extend the tags/list response with the tags, size and timestamps of every
+// manifest, so no manifest or config has to be fetched.
 func (p *GCPProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
-	repo, err := name.NewRepository(image)
+	ref, err := imageref.Parse(image)
 	if err != nil {
-		// If parsing as repository fails, try to extract repository from full image
-		ref, err := name.ParseReference(image)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse image reference %s: %v", image, err)
-		}
-		repo = ref.Context()
+		return nil, err
 	}
+	// Artifact Registry paths can be nested, e.g. <region>-docker.pkg.dev/<project>/<repository>/team/app
+	repo := ref.Context()
 
 	keychain := p.getKeychain()
 
-	tags, err := remote.List(repo, remote.WithAuthFromKeychain(keychain))
+	listing, err := google.List(repo, google.WithAuthFromKeychain(keychain))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tags for %s: %v", repo.String(), err)
 	}
 
-	if len(tags) == 0 {
-		return nil, fmt.Errorf("no tags found for image %s", repo.String())
+	if len(listing.Manifests) == 0 {
+		if len(listing.Children) > 0 {
+			return nil, fmt.Errorf("no images found in %s, which contains the repositories %s",
+				repo.String(), strings.Join(listing.Children, ", "))
+		}
+		if len(listing.Tags) == 0 {
+			return nil, fmt.Errorf("no tags found for image %s", repo.String())
+		}
+
+		// Registries without the manifest extension only return tag names
+		return tagsWithCreationTime(repo, listing.Tags, keychain), nil
 	}
 
-	tagInfos, err := p.getTagsWithCreationTime(repo, tags, keychain)
-	if err != nil {
-		// If we can't get creation times, fall back to alphabetical sort and create TagInfo with zero time
-		sort.Strings(tags)
-		tagInfos = make([]TagInfo, len(tags))
-		for i, tag := range tags {
-			tagInfos[i] = TagInfo{
+	var tagInfos []TagInfo
+	for digest, manifest := range listing.Manifests {
+		for _, tag := range manifest.Tags {
+			tagInfos = append(tagInfos, TagInfo{
 				Tag:       tag,
-				CreatedAt: time.Time{}, // Zero time indicates no timestamp available
-			}
+				CreatedAt: manifestTime(manifest),
+				Digest:    digest,
+				Size:      int64(manifest.Size),
+			})
 		}
-	} else {
-		// Sort by creation time (newest first)
-		sort.Slice(tagInfos, func(i, j int) bool {
-			return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
-		})
 	}
 
-	// Limit to 20 tags for performance
+	// Sort by creation time (newest first)
+	sort.Slice(tagInfos, func(i, j int) bool {
+		return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
+	})
+
+	// Limit to 20 tags for performance; untagged manifests come after them, so they
+	// can't push tags out of the list
 	if len(tagInfos) > 20 {
 		tagInfos = tagInfos[:20]
 	}
 
-	return tagInfos, nil
+	return append(tagInfos, untaggedManifests(repo, listing.Manifests, keychain, 20)...), nil
 }
 
-// getKeychain gets authentication keychain for GCP registries
-func (p *GCPProvider) getKeychain() authn.Keychain {
-	// Try to get auth from Application Default Credentials
-	if adcKeychain := p.getADCKeychain(); adcKeychain != nil {
-		return adcKeychain
+// manifestTime returns the creation time of a listed manifest, or its upload time for images
+// without one, such as some OCI artifacts, whose timeCreatedMs is 0
+func manifestTime(manifest google.ManifestInfo) time.Time {
+	if manifest.Created.Unix() <= 0 {
+		return manifest.Uploaded
 	}
-
-	// Fall back to default keychain
-	return authn.DefaultKeychain
+	return manifest.Created
 }
 
-// getADCKeychain attempts to create a keychain using Application Default Credentials
-func (p *GCPProvider) getADCKeychain() authn.Keychain {
-	ctx := context.Background()
+// untaggedManifests returns up to limit of the newest untagged manifests, leaving out the platform
+// images of multi-arch indexes, which are listed untagged too. An index is pushed after its platform
+// images, so only indexes uploaded at or after an untagged manifest are fetched to check whether
+// they list it.
+func untaggedManifests(repo name.Repository, manifests map[string]google.ManifestInfo, keychain authn.Keychain, limit int) []TagInfo {
+	var untagged, indexes []string
+	for digest, manifest := range manifests {
+		if types.MediaType(manifest.MediaType).IsIndex() {
+			indexes = append(indexes, digest)
+		}
+		if len(manifest.Tags) == 0 {
+			untagged = append(untagged, digest)
+		}
+	}
+	newestUpload := func(digests []string) {
+		sort.Slice(digests, func(i, j int) bool {
+			return manifests[digests[i]].Uploaded.After(manifests[digests[j]].Uploaded)
+		})
+	}
+	newestUpload(untagged)
+	newestUpload(indexes)
 
-	tokenSource, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
-	if err != nil {
-		return nil
+	children := make(map[string]bool)
+	fetched := 0
+	var tagInfos []TagInfo
+	for _, digest := range untagged {
+		if len(tagInfos) >= limit {
+			break
+		}
+
+		uploaded := manifests[digest].Uploaded
+		for ; fetched < len(indexes) && !manifests[indexes[fetched]].Uploaded.Before(uploaded); fetched++ {
+			platforms, err := indexChildren(repo.Digest(indexes[fetched]), keychain)
+			if err != nil {
+				// Log error but continue with other indexes
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			for _, child := range platforms {
+				children[child] = true
+			}
+		}
+		if children[digest] {
+			continue
+		}
+
+		tagInfos = append(tagInfos, TagInfo{
+			CreatedAt: manifestTime(manifests[digest]),
+			Digest:    digest,
+			Size:      int64(manifests[digest].Size),
+		})
 	}
 
-	return &adcKeychain{tokenSource: tokenSource}
+	return tagInfos
 }
 
-// getTagsWithCreationTime fetches creation time for each tag
-func (p *GCPProvider) getTagsWithCreationTime(repo name.Repository, tags []string, keychain authn.Keychain) ([]TagInfo, error) {
-	var tagInfos []TagInfo
-
-	maxConcurrent := 10
-	if len(tags) < maxConcurrent {
-		maxConcurrent = len(tags)
+// indexChildren returns the digests of the manifests an index lists
+func indexChildren(ref name.Digest, keychain authn.Keychain) ([]string, error) {
+	index, err := remote.Index(ref, remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image index %s: %v", ref.DigestStr(), err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image index %s: %v", ref.DigestStr(), err)
 	}
 
-	tagsToProcess := tags
-	if len(tags) > 50 {
-		tagsToProcess = tags[:50] // Limit to first 50 tags for performance
+	digests := make([]string, len(manifest.Manifests))
+	for i, child := range manifest.Manifests {
+		digests[i] = child.Digest.String()
 	}
+	return digests, nil
+}
 
-	results := make(chan TagInfo, len(tagsToProcess))
-	errors := make(chan error, len(tagsToProcess))
+// ResolveDigest returns the manifest digest an image reference points to
+func (p *GCPProvider) ResolveDigest(image string) (string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", err
+	}
 
-	sem := make(chan struct{}, maxConcurrent)
+	return headDigest(ref, p.getKeychain())
+}
 
-	for _, tag := range tagsToProcess {
-		go func(tag string) {
-			sem <- struct{}{}        // Acquire semaphore
-			defer func() { <-sem }() // Release semaphore
+// VerifyImage checks that an image reference exists and can be pulled
+func (p *GCPProvider) VerifyImage(image string) error {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return err
+	}
 
-			tagRef, err := name.ParseReference(fmt.Sprintf("%s:%s", repo.String(), tag))
-			if err != nil {
-				errors <- fmt.Errorf("failed to parse tag %s: %v", tag, err)
-				return
-			}
+	return verifyRemote(ref, p.getKeychain())
+}
 
-			// Get image manifest
-			img, err := remote.Image(tagRef, remote.WithAuthFromKeychain(keychain))
-			if err != nil {
-				errors <- fmt.Errorf("failed to get image for tag %s: %v", tag, err)
-				return
-			}
+// ImagePlatforms returns the platforms an image reference can run on
+func (p *GCPProvider) ImagePlatforms(image string) ([]Platform, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
 
-			// Get config to extract creation time
-			config, err := img.ConfigFile()
-			if err != nil {
-				errors <- fmt.Errorf("failed to get config for tag %s: %v", tag, err)
-				return
-			}
+	return imagePlatforms(ref, p.getKeychain())
+}
 
-			createdAt := config.Created.Time
-			if createdAt.IsZero() {
-				// If creation time is not available, use a default old time
-				createdAt = time.Unix(0, 0)
-			}
+// ListTagNames returns all tags of the image's repository
+func (p *GCPProvider) ListTagNames(image string) ([]string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
 
-			results <- TagInfo{
-				Tag:       tag,
-				CreatedAt: createdAt,
-			}
-		}(tag)
+	tags, err := remote.List(ref.Context(), remote.WithAuthFromKeychain(p.getKeychain()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", ref.Context(), err)
 	}
+	return tags, nil
+}
 
-	for i := 0; i < len(tagsToProcess); i++ {
-		select {
-		case tagInfo := <-results:
-			tagInfos = append(tagInfos, tagInfo)
-		case err := <-errors:
-			// Log error but continue with other tags
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-		}
+// getKeychain gets authentication keychain for GCP registries
+func (p *GCPProvider) getKeychain() authn.Keychain {
+	// Try to get auth from Application Default Credentials
+	if adcKeychain := p.getADCKeychain(); adcKeychain != nil {
+		return adcKeychain
 	}
 
-	if len(tagInfos) == 0 {
-		return nil, fmt.Errorf("failed to get creation time for any tags")
+	// Fall back to default keychain
+	return authn.DefaultKeychain
+}
+
+// getADCKeychain attempts to create a keychain using Application Default Credentials
+func (p *GCPProvider) getADCKeychain() authn.Keychain {
+	ctx := context.Background()
+
+	tokenSource, err := oauth2google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil
 	}
 
-	return tagInfos, nil
+	return &adcKeychain{tokenSource: tokenSource}
 }
 
 // adcKeychain implements authn.Keychain using Application Default Credentials
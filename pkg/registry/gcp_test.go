@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestUntaggedManifests(t *testing.T) {
+	host, _ := newTestRegistry(t)
+	repo, err := name.NewRepository(host + "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := random.Index(256, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(repo.Tag("v1"), index); err != nil {
+		t.Fatal(err)
+	}
+	indexDigest, err := index.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manifests := map[string]google.ManifestInfo{
+		indexDigest.String(): {MediaType: string(types.OCIImageIndex), Tags: []string{"v1"}, Uploaded: base.Add(time.Hour)},
+		// A single-arch image pushed by digest before the index, which doesn't list it
+		"sha256:single": {MediaType: string(types.DockerManifestSchema2), Uploaded: base},
+		// An untagged image pushed after the index
+		"sha256:later": {MediaType: string(types.DockerManifestSchema2), Uploaded: base.Add(2 * time.Hour)},
+	}
+	for _, child := range indexManifest.Manifests {
+		manifests[child.Digest.String()] = google.ManifestInfo{MediaType: string(child.MediaType), Uploaded: base.Add(time.Hour)}
+	}
+
+	var got []string
+	for _, tagInfo := range untaggedManifests(repo, manifests, authn.DefaultKeychain, 20) {
+		if tagInfo.Tag != "" {
+			t.Errorf("untagged manifest %s has tag %s", tagInfo.Digest, tagInfo.Tag)
+		}
+		got = append(got, tagInfo.Digest)
+	}
+	if len(got) != 2 || got[0] != "sha256:later" || got[1] != "sha256:single" {
+		t.Errorf("untaggedManifests() = %v, want the images no index lists, newest first: [sha256:later sha256:single]", got)
+	}
+
+	if got := untaggedManifests(repo, manifests, authn.DefaultKeychain, 1); len(got) != 1 || got[0].Digest != "sha256:later" {
+		t.Errorf("untaggedManifests() with limit 1 = %v, want only sha256:later", got)
+	}
+}
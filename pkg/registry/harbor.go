@@ -0,0 +1,349 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+)
+
+// harborSeverities are the vulnerability severities of Harbor scan summaries, most severe first
+var harborSeverities = []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+
+// HarborProvider handles Harbor registries on configured hostnames
+type HarborProvider struct {
+	hosts      map[string]bool
+	httpClient *http.Client
+}
+
+// NewHarborProvider creates a new Harbor registry provider for the given hostnames, with ports if any
+func NewHarborProvider(hosts []string) *HarborProvider {
+	p := &HarborProvider{
+		hosts:      make(map[string]bool),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, host := range hosts {
+		p.hosts[strings.ToLower(strings.TrimSpace(host))] = true
+	}
+	return p
+}
+
+// Name returns the provider name
+func (p *HarborProvider) Name() string {
+	return "Harbor"
+}
+
+// SupportsImage checks if this provider can handle the given image
+func (p *HarborProvider) SupportsImage(image string) bool {
+	// Harbor can run on any host, so only configured ones are matched
+	ref, err := imageref.Parse(image)
+	return err == nil && p.hosts[ref.Registry]
+}
+
+// ListTags fetches available tags for an image
+func (p *HarborProvider) ListTags(image string) ([]string, error) {
+	tagInfos, err := p.ListTagsWithInfo(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, len(tagInfos))
+	for i, tagInfo := range tagInfos {
+		tags[i] = tagInfo.Tag
+	}
+
+	return tags, nil
+}
+
+// harborArtifact is an artifact of the Harbor v2 artifacts API
+type harborArtifact struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	PushTime time.Time `json:"push_time"`
+	Tags     []struct {
+		Name      string    `json:"name"`
+		PushTime  time.Time `json:"push_time"`
+		Immutable bool      `json:"immutable"`
+		Signed    bool      `json:"signed"`
+	} `json:"tags"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	ExtraAttrs struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"extra_attrs"`
+	References []struct {
+		Platform *struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"references"`
+	Accessories []struct {
+		Type string `json:"type"`
+	} `json:"accessories"`
+	ScanOverview map[string]struct {
+		ScanStatus string `json:"scan_status"`
+		Summary    *struct {
+			Total   int            `json:"total"`
+			Fixable int            `json:"fixable"`
+			Summary map[string]int `json:"summary"`
+		} `json:"summary"`
+	} `json:"scan_overview"`
+}
+
+// ListTagsWithInfo fetches available tags with push time, size, platforms, scan summary,
+// signature, labels, immutability and retention rules from the Harbor v2 artifacts API
+func (p *HarborProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	project, repository, ok := strings.Cut(ref.Repository, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid Harbor image %s: expected <host>/<project>/<repository>[:tag]", image)
+	}
+
+	// Repository names with slashes must be escaped twice in the API path
+	endpoint := fmt.Sprintf("https://%s/api/v2.0/projects/%s/repositories/%s/artifacts?%s", ref.Registry,
+		url.PathEscape(project), url.PathEscape(url.PathEscape(repository)), url.Values{
+			"page_size":             {"100"},
+			"sort":                  {"-push_time"},
+			"with_tag":              {"true"},
+			"with_label":            {"true"},
+			"with_scan_overview":    {"true"},
+			"with_signature":        {"true"},
+			"with_immutable_status": {"true"},
+			"with_accessory":        {"true"},
+		}.Encode())
+
+	var artifacts []harborArtifact
+	for next := endpoint; next != ""; {
+		var page []harborArtifact
+		next, err = p.getJSON(ref.Registry, next, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts for %s: %v", ref.FullName(), err)
+		}
+		artifacts = append(artifacts, page...)
+	}
+
+	// Retention rules are project wide and need project admin rights, so they are best effort
+	retention, _ := p.retentionRules(ref.Registry, project)
+
+	var tagInfos []TagInfo
+	for _, artifact := range artifacts {
+		var architectures []string
+		if artifact.ExtraAttrs.Architecture != "" {
+			architectures = append(architectures, artifact.ExtraAttrs.OS+"/"+artifact.ExtraAttrs.Architecture)
+		}
+		for _, reference := range artifact.References {
+			if reference.Platform != nil && reference.Platform.OS != "unknown" {
+				architectures = append(architectures, reference.Platform.OS+"/"+reference.Platform.Architecture)
+			}
+		}
+
+		for _, tag := range artifact.Tags {
+			createdAt := tag.PushTime
+			if createdAt.IsZero() {
+				createdAt = artifact.PushTime
+			}
+
+			tagInfos = append(tagInfos, TagInfo{
+				Tag:           tag.Name,
+				CreatedAt:     createdAt,
+				Digest:        artifact.Digest,
+				Size:          artifact.Size,
+				Architectures: architectures,
+				Details:       append(artifact.details(tag.Immutable, tag.Signed), retention...),
+			})
+		}
+	}
+
+	if len(tagInfos) == 0 {
+		return nil, fmt.Errorf("no tagged artifacts found in Harbor repository %s", ref.FullName())
+	}
+
+	// Sort by creation time (newest first)
+	sort.Slice(tagInfos, func(i, j int) bool {
+		return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
+	})
+
+	// Limit to 20 tags for performance
+	if len(tagInfos) > 20 {
+		tagInfos = tagInfos[:20]
+	}
+
+	return tagInfos, nil
+}
+
+// details describes the immutability, signature, scan summary and labels of a tagged artifact
+func (a harborArtifact) details(immutable, signed bool) []string {
+	var details []string
+	if immutable {
+		details = append(details, "immutable tag")
+	}
+
+	// Notary signatures are reported per tag, cosign and notation ones as accessories
+	for _, accessory := range a.Accessories {
+		if strings.HasPrefix(accessory.Type, "signature.") {
+			signed = true
+		}
+	}
+	if signed {
+		details = append(details, "signed")
+	} else {
+		details = append(details, "not signed")
+	}
+
+	for _, report := range a.ScanOverview {
+		if report.Summary == nil {
+			details = append(details, fmt.Sprintf("scan: %s", strings.ToLower(report.ScanStatus)))
+			continue
+		}
+		if report.Summary.Total == 0 {
+			details = append(details, "scan: no vulnerabilities")
+			continue
+		}
+
+		var counts []string
+		for _, severity := range harborSeverities {
+			if n := report.Summary.Summary[severity]; n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", n, strings.ToLower(severity)))
+			}
+		}
+		details = append(details, fmt.Sprintf("scan: %d vulnerabilities (%s), %d fixable",
+			report.Summary.Total, strings.Join(counts, ", "), report.Summary.Fixable))
+	}
+
+	if len(a.Labels) > 0 {
+		labels := make([]string, len(a.Labels))
+		for i, label := range a.Labels {
+			labels[i] = label.Name
+		}
+		details = append(details, "labels: "+strings.Join(labels, ", "))
+	}
+	return details
+}
+
+// harborRetention is a tag retention policy of a Harbor project
+type harborRetention struct {
+	Rules []struct {
+		Disabled     bool                   `json:"disabled"`
+		Action       string                 `json:"action"`
+		Template     string                 `json:"template"`
+		Params       map[string]interface{} `json:"params"`
+		TagSelectors []struct {
+			Decoration string `json:"decoration"`
+			Pattern    string `json:"pattern"`
+		} `json:"tag_selectors"`
+		ScopeSelectors map[string][]struct {
+			Decoration string `json:"decoration"`
+			Pattern    string `json:"pattern"`
+		} `json:"scope_selectors"`
+	} `json:"rules"`
+}
+
+// retentionRules describes the tag retention rules of a project, such as
+// "retention: keep the 10 most recently pushed, tags matching **, repositories matching **"
+func (p *HarborProvider) retentionRules(registry, project string) ([]string, error) {
+	var projectInfo struct {
+		Metadata struct {
+			RetentionID string `json:"retention_id"`
+		} `json:"metadata"`
+	}
+	if _, err := p.getJSON(registry, fmt.Sprintf("https://%s/api/v2.0/projects/%s", registry, url.PathEscape(project)), &projectInfo); err != nil {
+		return nil, err
+	}
+	if projectInfo.Metadata.RetentionID == "" {
+		return nil, nil
+	}
+
+	var retention harborRetention
+	if _, err := p.getJSON(registry, fmt.Sprintf("https://%s/api/v2.0/retentions/%s", registry, url.PathEscape(projectInfo.Metadata.RetentionID)), &retention); err != nil {
+		return nil, err
+	}
+
+	var rules []string
+	for _, rule := range retention.Rules {
+		if rule.Disabled {
+			continue
+		}
+
+		var description string
+		n := rule.Params[rule.Template]
+		switch rule.Template {
+		case "latestPushedK":
+			description = fmt.Sprintf("keep the %v most recently pushed", n)
+		case "latestPulledN":
+			description = fmt.Sprintf("keep the %v most recently pulled", n)
+		case "nDaysSinceLastPush":
+			description = fmt.Sprintf("keep those pushed within %v days", n)
+		case "nDaysSinceLastPull":
+			description = fmt.Sprintf("keep those pulled within %v days", n)
+		case "always":
+			description = "always keep"
+		default:
+			description = fmt.Sprintf("%s %s", rule.Action, rule.Template)
+		}
+
+		for _, selector := range rule.TagSelectors {
+			description += fmt.Sprintf(", tags %s %s", selectorVerb(selector.Decoration), selector.Pattern)
+		}
+		for _, selector := range rule.ScopeSelectors["repository"] {
+			description += fmt.Sprintf(", repositories %s %s", selectorVerb(selector.Decoration), selector.Pattern)
+		}
+		rules = append(rules, "retention: "+description)
+	}
+	return rules, nil
+}
+
+// selectorVerb turns a retention selector decoration such as "repoExcludes" into "excluding"
+func selectorVerb(decoration string) string {
+	if strings.HasSuffix(strings.ToLower(decoration), "excludes") {
+		return "excluding"
+	}
+	return "matching"
+}
+
+// getJSON calls the Harbor API with the docker config credentials of the registry, if any, and
+// returns the URL of the next page from the Link header, or "" on the last page
+func (p *HarborProvider) getJSON(registry, endpoint string, result interface{}) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// The API takes the same username/password or robot account as docker login
+	if resource, err := name.NewRegistry(registry); err == nil {
+		if authenticator, err := authn.DefaultKeychain.Resolve(resource); err == nil {
+			if auth, err := authenticator.Authorization(); err == nil && auth.Username != "" {
+				req.SetBasicAuth(auth.Username, auth.Password)
+			}
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", err
+	}
+
+	return nextLink(resp, endpoint), nil
+}
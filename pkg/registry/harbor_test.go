@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHarborAPI starts a stub of the Harbor v2 API for harbor.example.com, logged in to as a
+// robot account, and returns a provider using it
+func newHarborAPI(t *testing.T, handler http.HandlerFunc) *HarborProvider {
+	t.Helper()
+
+	dockerConfig := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dockerConfig)
+	auth := base64.StdEncoding.EncodeToString([]byte("robot$ci:secret"))
+	config := fmt.Sprintf(`{"auths": {"harbor.example.com": {"auth": %q}}}`, auth)
+	if err := os.WriteFile(filepath.Join(dockerConfig, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "robot$ci" || password != "secret" {
+			t.Errorf("%s called without the docker config credentials", r.URL)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewHarborProvider([]string{"harbor.example.com"})
+	p.httpClient = &http.Client{Transport: &registryRedirect{
+		host: strings.TrimPrefix(server.URL, "http://"),
+		next: http.DefaultTransport,
+	}}
+	return p
+}
+
+func TestHarborProviderListTagsWithInfo(t *testing.T) {
+	artifacts := "/api/v2.0/projects/library/repositories/team%252Fapp/artifacts"
+	p := newHarborAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2.0/projects/library/repositories/team%2Fapp/artifacts":
+			// Harbor lists the previous page before the next one
+			switch page := r.URL.Query().Get("page"); page {
+			case "":
+				w.Header().Set("Link", fmt.Sprintf(`<%s?page=2&page_size=100>; rel="next"`, artifacts))
+				fmt.Fprint(w, `[{
+					"digest": "sha256:index", "size": 100, "push_time": "2024-03-01T00:00:00Z",
+					"tags": [{"name": "v3", "push_time": "2024-03-01T00:00:00Z", "immutable": true}],
+					"labels": [{"name": "prod"}],
+					"references": [
+						{"platform": {"architecture": "amd64", "os": "linux"}},
+						{"platform": {"architecture": "unknown", "os": "unknown"}}
+					],
+					"accessories": [{"type": "signature.cosign"}],
+					"scan_overview": {"application/vnd.security.vulnerability.report; version=1.1": {
+						"scan_status": "Success",
+						"summary": {"total": 3, "fixable": 1, "summary": {"Critical": 1, "High": 2}}
+					}}
+				}]`)
+			case "2":
+				w.Header().Set("Link", fmt.Sprintf(`<%s?page=1&page_size=100>; rel="prev" , <%s?page=3&page_size=100>; rel="next"`, artifacts, artifacts))
+				fmt.Fprint(w, `[{"digest": "sha256:v2", "size": 90, "push_time": "2024-02-01T00:00:00Z", "tags": [{"name": "v2"}]}]`)
+			case "3":
+				w.Header().Set("Link", fmt.Sprintf(`<%s?page=2&page_size=100>; rel="prev"`, artifacts))
+				fmt.Fprint(w, `[{"digest": "sha256:v1", "size": 80, "push_time": "2024-01-01T00:00:00Z", "tags": [{"name": "v1", "signed": true}],
+					"extra_attrs": {"architecture": "arm64", "os": "linux"}}]`)
+			default:
+				t.Errorf("unexpected artifacts page %s", page)
+				http.NotFound(w, r)
+			}
+		case "/api/v2.0/projects/library":
+			fmt.Fprint(w, `{"metadata": {"retention_id": "7"}}`)
+		case "/api/v2.0/retentions/7":
+			fmt.Fprint(w, `{"rules": [
+				{"action": "retain", "template": "latestPushedK", "params": {"latestPushedK": 10},
+					"tag_selectors": [{"decoration": "matches", "pattern": "**"}],
+					"scope_selectors": {"repository": [{"decoration": "repoExcludes", "pattern": "tmp/**"}]}},
+				{"disabled": true, "action": "retain", "template": "always"}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("harbor.example.com/library/team/app:v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tagInfo := range tagInfos {
+		got = append(got, fmt.Sprintf("%s %s %s", tagInfo.Tag, tagInfo.Digest, strings.Join(tagInfo.Architectures, ",")))
+	}
+	want := []string{
+		"v3 sha256:index linux/amd64",
+		"v2 sha256:v2 ",
+		"v1 sha256:v1 linux/arm64",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("ListTagsWithInfo() from three pages =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	retention := "retention: keep the 10 most recently pushed, tags matching **, repositories excluding tmp/**"
+	wantDetails := [][]string{
+		{"immutable tag", "signed", "scan: 3 vulnerabilities (1 critical, 2 high), 1 fixable", "labels: prod", retention},
+		{"not signed", retention},
+		{"signed", retention},
+	}
+	for i, tagInfo := range tagInfos {
+		if got, want := strings.Join(tagInfo.Details, "; "), strings.Join(wantDetails[i], "; "); got != want {
+			t.Errorf("details of %s = %s, want %s", tagInfo.Tag, got, want)
+		}
+	}
+}
+
+func TestHarborProviderRetentionUnreadable(t *testing.T) {
+	p := newHarborAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2.0/projects/library/repositories/app/artifacts":
+			fmt.Fprint(w, `[{"digest": "sha256:v1", "push_time": "2024-01-01T00:00:00Z", "tags": [{"name": "v1"}]}]`)
+		case "/api/v2.0/projects/library":
+			fmt.Fprint(w, `{"metadata": {"retention_id": "7"}}`)
+		default:
+			// Retention policies need project admin rights
+			http.Error(w, `{"errors": [{"code": "FORBIDDEN"}]}`, http.StatusForbidden)
+		}
+	})
+
+	tagInfos, err := p.ListTagsWithInfo("harbor.example.com/library/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagInfos) != 1 || strings.Join(tagInfos[0].Details, "; ") != "not signed" {
+		t.Errorf("ListTagsWithInfo() = %+v, want v1 without retention rules", tagInfos)
+	}
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+)
+
+// ociPageSize is the default number of tags requested per page of /tags/list
+const ociPageSize = 1000
+
+// OCIProvider handles any registry implementing the OCI distribution spec, such as registry:2,
+// Zot, Nexus, GHCR, Quay or GitLab. It is registered last and supports every image.
+type OCIProvider struct {
+	keychain authn.Keychain
+	pageSize int
+}
+
+// NewOCIProvider creates a new generic OCI distribution registry provider
+func NewOCIProvider() *OCIProvider {
+	return &OCIProvider{keychain: authn.DefaultKeychain, pageSize: ociPageSize}
+}
+
+// Name returns the provider name
+func (p *OCIProvider) Name() string {
+	return "OCI registry"
+}
+
+// SupportsImage checks if this provider can handle the given image
+func (p *OCIProvider) SupportsImage(image string) bool {
+	// Every registry speaks the distribution API, so any valid reference is supported
+	_, err := imageref.Parse(image)
+	return err == nil
+}
+
+// ListTags fetches available tags for an image
+func (p *OCIProvider) ListTags(image string) ([]string, error) {
+	tagInfos, err := p.ListTagsWithInfo(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, len(tagInfos))
+	for i, tagInfo := range tagInfos {
+		tags[i] = tagInfo.Tag
+	}
+
+	return tags, nil
+}
+
+// ListTagsWithInfo fetches available tags with creation time info from the image config blobs
+func (p *OCIProvider) ListTagsWithInfo(image string) ([]TagInfo, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	repo := ref.Context()
+
+	tags, err := p.listTags(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", repo.String(), err)
+	}
+
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags found for image %s", repo.String())
+	}
+
+	return tagsWithCreationTime(repo, tags, p.keychain), nil
+}
+
+// ListTagNames returns all tags of the image's repository
+func (p *OCIProvider) ListTagNames(image string) ([]string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := p.listTags(ref.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", ref.Context(), err)
+	}
+	return tags, nil
+}
+
+// listTags pages through /v2/<repository>/tags/list, following the Link header of each page.
+// The transport answers the WWW-Authenticate challenge of the registry, exchanging the keychain
+// credentials for a bearer token scoped to pulling the repository, or using basic auth.
+func (p *OCIProvider) listTags(repo name.Repository) ([]string, error) {
+	ctx := context.Background()
+
+	auth, err := authn.Resolve(ctx, p.keychain, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := transport.NewWithContext(ctx, repo.Registry, auth, remote.DefaultTransport, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: rt}
+
+	next := (&url.URL{
+		Scheme:   repo.Registry.Scheme(),
+		Host:     repo.RegistryStr(),
+		Path:     fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+		RawQuery: fmt.Sprintf("n=%d", p.pageSize),
+	}).String()
+
+	var tags []string
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := transport.CheckError(resp, http.StatusOK); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tag list: %v", err)
+		}
+
+		tags = append(tags, page.Tags...)
+		next = nextLink(resp, next)
+	}
+
+	return tags, nil
+}
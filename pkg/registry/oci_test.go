@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// linkPaging adds the Link header of the distribution spec to full /tags/list pages, which the
+// in-process registry only cuts with n and last
+func linkPaging(t *testing.T, next http.Handler, pages *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := r.URL.Query().Get("n")
+		if !strings.HasSuffix(r.URL.Path, "/tags/list") || n == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		*pages++
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Errorf("failed to decode tag list: %v", err)
+		}
+		if len(page.Tags) > 0 && fmt.Sprint(len(page.Tags)) == n {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?n=%s&last=%s>; rel="next"`, r.URL.Path, n, page.Tags[len(page.Tags)-1]))
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+// newTestRegistry starts an in-process registry and returns its host and the number of
+// /tags/list pages served so far
+func newTestRegistry(t *testing.T) (string, *int) {
+	t.Helper()
+	// Keep credentials of the machine running the tests out of the keychain
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	pages := new(int)
+	server := httptest.NewServer(linkPaging(t, ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0))), pages))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://"), pages
+}
+
+// pushImage pushes a random image whose config has the given creation time
+func pushImage(t *testing.T, image string, created time.Time) v1.Hash {
+	t.Helper()
+
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = mutate.CreatedAt(img, v1.Time{Time: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("failed to push %s: %v", image, err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return digest
+}
+
+func TestOCIProviderListTagsPaging(t *testing.T) {
+	host, pages := newTestRegistry(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, tag := range []string{"a", "b", "c", "d", "e"} {
+		pushImage(t, fmt.Sprintf("%s/team/app:%s", host, tag), base.Add(time.Duration(i)*time.Hour))
+	}
+
+	p := NewOCIProvider()
+	p.pageSize = 2
+
+	tags, err := p.ListTagNames(host + "/team/app:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(tags, ","); got != "a,b,c,d,e" {
+		t.Errorf("ListTagNames() = %s, want a,b,c,d,e", got)
+	}
+	// Two full pages with a Link header, then the last one without
+	if *pages != 3 {
+		t.Errorf("fetched %d pages, want 3", *pages)
+	}
+}
+
+func TestOCIProviderListTagsWithInfo(t *testing.T) {
+	host, _ := newTestRegistry(t)
+	created := map[string]time.Time{
+		"v1": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		"v2": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"v3": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	digests := make(map[string]string)
+	for tag, at := range created {
+		digests[tag] = pushImage(t, fmt.Sprintf("%s/app:%s", host, tag), at).String()
+	}
+
+	tagInfos, err := NewOCIProvider().ListTagsWithInfo(host + "/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, tagInfo := range tagInfos {
+		order = append(order, tagInfo.Tag)
+		if !tagInfo.CreatedAt.Equal(created[tagInfo.Tag]) {
+			t.Errorf("tag %s created at %v, want %v from its config", tagInfo.Tag, tagInfo.CreatedAt, created[tagInfo.Tag])
+		}
+		if tagInfo.Digest != digests[tagInfo.Tag] {
+			t.Errorf("tag %s has digest %s, want %s", tagInfo.Tag, tagInfo.Digest, digests[tagInfo.Tag])
+		}
+		if tagInfo.Size == 0 {
+			t.Errorf("tag %s has no size", tagInfo.Tag)
+		}
+	}
+	if got := strings.Join(order, ","); got != "v2,v3,v1" {
+		t.Errorf("tags ordered %s, want newest first: v2,v3,v1", got)
+	}
+}
+
+func TestNewClientOCIFallback(t *testing.T) {
+	host, _ := newTestRegistry(t)
+	image := host + "/team/app:v1"
+
+	c := NewClient()
+	if _, ok := c.findProvider(image).(*OCIProvider); !ok {
+		t.Errorf("provider for %s is %s, want the OCI provider", image, c.findProvider(image).Name())
+	}
+	if _, ok := c.findProvider("nginx:1.27").(*DockerHubProvider); !ok {
+		t.Errorf("provider for nginx is %s, want Docker Hub", c.findProvider("nginx:1.27").Name())
+	}
+
+	c.AddProvider(NewHarborProvider([]string{host}))
+	if _, ok := c.findProvider(image).(*HarborProvider); !ok {
+		t.Errorf("provider for %s is %s after AddProvider, want Harbor", image, c.findProvider(image).Name())
+	}
+	if _, ok := c.providers[len(c.providers)-1].(*OCIProvider); !ok {
+		t.Errorf("last provider is %s, want the OCI provider", c.providers[len(c.providers)-1].Name())
+	}
+}
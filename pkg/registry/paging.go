@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// nextLink resolves the rel="next" URL of a Link header against the request URL. The header may
+// list other relations too, as Harbor sends rel="prev" before rel="next".
+func nextLink(resp *http.Response, endpoint string) string {
+	rest := resp.Header.Get("Link")
+	for {
+		start := strings.Index(rest, "<")
+		end := strings.Index(rest, ">")
+		if start < 0 || end < start {
+			return ""
+		}
+		target := rest[start+1 : end]
+		rest = rest[end+1:]
+
+		params, _, _ := strings.Cut(rest, "<")
+		if !strings.Contains(params, `rel="next"`) || target == "" {
+			continue
+		}
+
+		base, err := url.Parse(endpoint)
+		if err != nil {
+			return ""
+		}
+		next, err := base.Parse(target)
+		if err != nil {
+			return ""
+		}
+		return next.String()
+	}
+}
@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+)
+
+// Platform is an OS and CPU architecture an image is built for
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// String returns the platform in "os/arch[/variant]" form
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Architecture, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// PlatformLister is implemented by providers that need their own credentials to read manifests
+type PlatformLister interface {
+	// ImagePlatforms returns the platforms an image reference can run on
+	ImagePlatforms(image string) ([]Platform, error)
+}
+
+// ImagePlatforms returns the platforms of an image: every entry of a multi-arch image index,
+// or the platform in the config of a single-arch image
+func (c *Client) ImagePlatforms(image string) ([]Platform, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if lister, ok := c.findProvider(image).(PlatformLister); ok {
+		return lister.ImagePlatforms(image)
+	}
+
+	return imagePlatforms(ref, authn.DefaultKeychain)
+}
+
+// imagePlatforms reads the platforms of a reference from its index or image config
+func imagePlatforms(ref imageref.Reference, keychain authn.Keychain) ([]Platform, error) {
+	desc, err := remote.Get(ref.Remote(), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest of %s: %v", ref, err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index of %s: %v", ref, err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index of %s: %v", ref, err)
+		}
+
+		var platforms []Platform
+		for _, m := range manifest.Manifests {
+			if platform, ok := platformOf(m.Platform); ok {
+				platforms = append(platforms, platform)
+			}
+		}
+		return platforms, nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %v", ref, err)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config of %s: %v", ref, err)
+	}
+	if config.Architecture == "" {
+		return nil, nil
+	}
+	return []Platform{{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}}, nil
+}
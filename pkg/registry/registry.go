@@ -2,13 +2,29 @@ package registry
 
 import (
 	"fmt"
+	"slices"
 	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
 )
 
-// TagInfo holds tag name and creation time for sorting
+// TagInfo holds tag name and creation time for sorting. Tag is empty for an untagged manifest,
+// identified by Digest, where the provider lists those.
 type TagInfo struct {
 	Tag       string
 	CreatedAt time.Time
+
+	// Digest, Size (compressed, in bytes) and Architectures ("os/arch[/variant]") are set
+	// where the provider knows them
+	Digest        string
+	Size          int64
+	Architectures []string
+
+	// Details are provider specific facts such as scan results or retention rules
+	Details []string
 }
 
 // Provider interface for different container registries
@@ -26,6 +42,12 @@ type Provider interface {
 	Name() string
 }
 
+// DigestResolver is implemented by providers that need their own credentials to resolve digests
+type DigestResolver interface {
+	// ResolveDigest returns the manifest digest an image reference points to
+	ResolveDigest(image string) (string, error)
+}
+
 // Client manages multiple registry providers
 type Client struct {
 	providers []Provider
@@ -37,16 +59,16 @@ func NewClient() *Client {
 		providers: []Provider{
 			NewAWSProvider(),       // AWS ECR - check first for specific domain matching
 			NewGCPProvider(),       // GCP GCR/Artifact Registry
-			NewDockerHubProvider(), // Docker Hub - should be last as it's the most generic
-			// Future providers can be added here:
-			// NewAzureProvider(),
+			NewAzureProvider(),     // Azure ACR
+			NewDockerHubProvider(), // Docker Hub
+			NewOCIProvider(),       // Any OCI distribution registry - must be last as it supports every image
 		},
 	}
 }
 
-// AddProvider adds a custom provider to the client
+// AddProvider adds a custom provider to the client, ahead of the generic OCI provider
 func (c *Client) AddProvider(provider Provider) {
-	c.providers = append(c.providers, provider)
+	c.providers = slices.Insert(c.providers, len(c.providers)-1, provider)
 }
 
 // ListTags fetches available tags for an image using the appropriate provider
@@ -69,6 +91,33 @@ func (c *Client) ListTagsWithInfo(image string) ([]TagInfo, error) {
 	return provider.ListTagsWithInfo(image)
 }
 
+// ResolveDigest returns the manifest digest an image points to, such as "sha256:..."
+func (c *Client) ResolveDigest(image string) (string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	if resolver, ok := c.findProvider(image).(DigestResolver); ok {
+		return resolver.ResolveDigest(image)
+	}
+
+	return headDigest(ref, authn.DefaultKeychain)
+}
+
+// headDigest resolves a reference to a manifest digest with a HEAD request
+func headDigest(ref imageref.Reference, keychain authn.Keychain) (string, error) {
+	desc, err := remote.Head(ref.Remote(), remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest of %s: %v", ref, err)
+	}
+	return desc.Digest.String(), nil
+}
+
 // findProvider finds the appropriate provider for an image
 func (c *Client) findProvider(image string) Provider {
 	for _, provider := range c.providers {
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// platformOf converts an index entry platform, skipping attestation manifests listed as unknown/unknown
+func platformOf(platform *v1.Platform) (Platform, bool) {
+	if platform == nil || platform.OS == "unknown" {
+		return Platform{}, false
+	}
+	return Platform{OS: platform.OS, Architecture: platform.Architecture, Variant: platform.Variant}, true
+}
+
+// describeTag fetches the digest, creation time, compressed size and platforms of a tag.
+// For a multi-arch index, time and size are those of the linux/amd64 image, or else the first one.
+func describeTag(ref name.Tag, keychain authn.Keychain) (TagInfo, error) {
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(keychain))
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("failed to get manifest for tag %s: %v", ref.TagStr(), err)
+	}
+	info := TagInfo{Tag: ref.TagStr(), Digest: desc.Digest.String()}
+
+	var img v1.Image
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return TagInfo{}, fmt.Errorf("failed to read image index for tag %s: %v", ref.TagStr(), err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return TagInfo{}, fmt.Errorf("failed to read image index for tag %s: %v", ref.TagStr(), err)
+		}
+
+		var chosen *v1.Hash
+		for _, m := range manifest.Manifests {
+			platform, ok := platformOf(m.Platform)
+			if !ok {
+				continue
+			}
+			info.Architectures = append(info.Architectures, platform.String())
+			if chosen == nil || (platform.OS == "linux" && platform.Architecture == "amd64") {
+				digest := m.Digest
+				chosen = &digest
+			}
+		}
+		if chosen == nil {
+			return info, nil
+		}
+		if img, err = index.Image(*chosen); err != nil {
+			return TagInfo{}, fmt.Errorf("failed to get image for tag %s: %v", ref.TagStr(), err)
+		}
+	} else if img, err = desc.Image(); err != nil {
+		return TagInfo{}, fmt.Errorf("failed to get image for tag %s: %v", ref.TagStr(), err)
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("failed to get config for tag %s: %v", ref.TagStr(), err)
+	}
+	info.CreatedAt = config.Created.Time
+	if len(info.Architectures) == 0 && config.Architecture != "" {
+		platform := Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}
+		info.Architectures = []string{platform.String()}
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("failed to get manifest for tag %s: %v", ref.TagStr(), err)
+	}
+	info.Size = manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		info.Size += layer.Size
+	}
+
+	return info, nil
+}
+
+// tagsWithCreationTime describes tags from their manifests and configs and returns the 20 newest.
+// If no tag can be described, the tags are returned in alphabetical order without timestamps.
+func tagsWithCreationTime(repo name.Repository, tags []string, keychain authn.Keychain) []TagInfo {
+	tagInfos, err := describeTags(repo, tags, keychain)
+	if err != nil {
+		// If we can't get creation times, fall back to alphabetical sort and create TagInfo with zero time
+		sort.Strings(tags)
+		tagInfos = make([]TagInfo, len(tags))
+		for i, tag := range tags {
+			tagInfos[i] = TagInfo{
+				Tag:       tag,
+				CreatedAt: time.Time{}, // Zero time indicates no timestamp available
+			}
+		}
+	} else {
+		// Sort by creation time (newest first)
+		sort.Slice(tagInfos, func(i, j int) bool {
+			return tagInfos[i].CreatedAt.After(tagInfos[j].CreatedAt)
+		})
+	}
+
+	// Limit to 20 tags for performance
+	if len(tagInfos) > 20 {
+		tagInfos = tagInfos[:20]
+	}
+
+	return tagInfos
+}
+
+// describeTags fetches creation time for each tag, 10 at a time
+func describeTags(repo name.Repository, tags []string, keychain authn.Keychain) ([]TagInfo, error) {
+	var tagInfos []TagInfo
+
+	maxConcurrent := 10
+	if len(tags) < maxConcurrent {
+		maxConcurrent = len(tags)
+	}
+
+	tagsToProcess := tags
+	if len(tags) > 50 {
+		tagsToProcess = tags[:50] // Limit to first 50 tags for performance
+	}
+
+	results := make(chan TagInfo, len(tagsToProcess))
+	errors := make(chan error, len(tagsToProcess))
+
+	sem := make(chan struct{}, maxConcurrent)
+
+	for _, tag := range tagsToProcess {
+		go func(tag string) {
+			sem <- struct{}{}        // Acquire semaphore
+			defer func() { <-sem }() // Release semaphore
+
+			tagRef := repo.Tag(tag)
+
+			// Get digest, creation time, size and platforms from the manifest and config
+			tagInfo, err := describeTag(tagRef, keychain)
+			if err != nil {
+				errors <- err
+				return
+			}
+
+			results <- tagInfo
+		}(tag)
+	}
+
+	for i := 0; i < len(tagsToProcess); i++ {
+		select {
+		case tagInfo := <-results:
+			tagInfos = append(tagInfos, tagInfo)
+		case err := <-errors:
+			// Log error but continue with other tags
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if len(tagInfos) == 0 {
+		return nil, fmt.Errorf("failed to get creation time for any tags")
+	}
+
+	return tagInfos, nil
+}
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
+)
+
+// maxSuggestions is the number of close tags offered when an image is not found
+const maxSuggestions = 3
+
+// ImageVerifier is implemented by providers that need their own credentials to check an image
+type ImageVerifier interface {
+	// VerifyImage checks that an image reference exists and can be pulled
+	VerifyImage(image string) error
+}
+
+// TagNameLister is implemented by providers that need their own credentials to list tag names
+type TagNameLister interface {
+	// ListTagNames returns all tags of the image's repository, without fetching their metadata
+	ListTagNames(image string) ([]string, error)
+}
+
+// NotFoundError reports an image that doesn't exist in its registry
+type NotFoundError struct {
+	Image string
+
+	// Suggestions are existing tags close to the requested one
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("image %s not found", e.Image)
+	}
+	return fmt.Sprintf("image %s not found, did you mean %s?", e.Image, strings.Join(e.Suggestions, ", "))
+}
+
+// VerifyImage checks that an image exists and can be pulled with the local credentials.
+// A missing tag is reported as a *NotFoundError with the closest existing tags.
+func (c *Client) VerifyImage(image string) error {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return err
+	}
+
+	provider := c.findProvider(image)
+	if verifier, ok := provider.(ImageVerifier); ok {
+		err = verifier.VerifyImage(image)
+	} else {
+		err = verifyRemote(ref, authn.DefaultKeychain)
+	}
+
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+
+	// Offer tags close to a mistyped one; failing to list them only loses the hint
+	if ref.Tag != "" && ref.Digest == "" {
+		var tags []string
+		if lister, ok := provider.(TagNameLister); ok {
+			tags, err = lister.ListTagNames(image)
+		} else {
+			tags, err = remote.List(ref.Context(), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+		}
+		if err == nil {
+			notFound.Suggestions = closestTags(ref.Tag, tags)
+		}
+	}
+	return notFound
+}
+
+// verifyRemote checks a reference with a HEAD request for its manifest
+func verifyRemote(ref imageref.Reference, keychain authn.Keychain) error {
+	_, err := remote.Head(ref.Remote(), remote.WithAuthFromKeychain(keychain))
+	if err == nil {
+		return nil
+	}
+
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		if terr.StatusCode == http.StatusNotFound {
+			return &NotFoundError{Image: ref.String()}
+		}
+		for _, diagnostic := range terr.Errors {
+			if diagnostic.Code == transport.ManifestUnknownErrorCode || diagnostic.Code == transport.NameUnknownErrorCode {
+				return &NotFoundError{Image: ref.String()}
+			}
+		}
+		if terr.StatusCode == http.StatusUnauthorized || terr.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("no pull access to %s with the local credentials (or the repository doesn't exist): %v", ref, err)
+		}
+	}
+	return fmt.Errorf("failed to check %s: %v", ref, err)
+}
+
+// closestTags returns up to maxSuggestions tags within a small edit distance of tag, closest first
+func closestTags(tag string, tags []string) []string {
+	maxDistance := len(tag) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	distances := make(map[string]int)
+	var candidates []string
+	for _, candidate := range tags {
+		if d := editDistance(tag, candidate); d <= maxDistance {
+			distances[candidate] = d
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if distances[candidates[i]] != distances[candidates[j]] {
+			return distances[candidates[i]] < distances[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	return candidates
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -1,17 +1,21 @@
 package tui
 
 import (
-	"context"
+	"cmp"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+
+	"github.com/tkuchiki/kubectl-setimg/pkg/imageref"
 )
 
 var (
@@ -21,6 +25,8 @@ var (
 	paginationStyle   = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
 	helpStyle         = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
 	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 2, 4)
+	detailStyle       = lipgloss.NewStyle().MarginLeft(4).Padding(0, 1).Border(lipgloss.RoundedBorder())
+	warningStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 )
 
 type item struct {
@@ -33,6 +39,10 @@ type listModel struct {
 	list   list.Model
 	choice string
 	quit   bool
+
+	// details renders an optional pane for the highlighted item, looked up by its title as the
+	// list index shifts when the list is filtered
+	details func(title string) string
 }
 
 func (m listModel) Init() tea.Cmd {
@@ -89,6 +99,11 @@ func (m listModel) View() string {
 	if m.quit {
 		return quitTextStyle.Render("Cancelled.")
 	}
+	if selected, ok := m.list.SelectedItem().(item); ok && m.details != nil {
+		if details := m.details(selected.title); details != "" {
+			return "\n" + m.list.View() + "\n" + detailStyle.Render(details)
+		}
+	}
 	return "\n" + m.list.View()
 }
 
@@ -123,27 +138,105 @@ type ContainerInfo struct {
 	Name  string
 	Image string
 	Index int
+	Init  bool
+
+	// Pods and Warnings describe what is actually running, shown in a detail pane
+	Pods     []PodGroup
+	Warnings []string
+}
+
+// PodGroup is a set of pods running a container from the same digest and ReplicaSet
+type PodGroup struct {
+	Revision string
+	Digest   string
+	Image    string
+	Pods     []string
+	Ready    int
+}
+
+// podDetails renders the running pods of a container for the detail pane
+func podDetails(container ContainerInfo) string {
+	if len(container.Pods) == 0 && len(container.Warnings) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for _, group := range container.Pods {
+		revision := group.Revision
+		if revision == "" {
+			revision = "-"
+		}
+		digest := group.Digest
+		if digest == "" {
+			digest = "<pending>"
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s  %d/%d ready  %s", revision, digest, group.Ready, len(group.Pods), group.Image))
+		lines = append(lines, "  "+strings.Join(group.Pods, ", "))
+	}
+	for _, warning := range container.Warnings {
+		lines = append(lines, warningStyle.Render("⚠ "+warning))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WorkloadInfo represents workload information
+type WorkloadInfo struct {
+	Kind    string
+	Name    string
+	Summary string
 }
 
-// TagInfo holds tag name and creation time for sorting
+// String returns the workload in "kind/name" form
+func (w WorkloadInfo) String() string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(w.Kind), w.Name)
+}
+
+// TagInfo holds a tag and what the registry knows about it; unknown fields are left empty.
+// Tag is empty for an untagged manifest, which is then picked by its digest.
 type TagInfo struct {
-	Tag       string
-	CreatedAt interface{} // Using interface{} to avoid importing time package here
+	Tag           string
+	CreatedAt     time.Time
+	Digest        string
+	Size          int64
+	Architectures []string
+
+	// Details are provider specific facts, shown in a pane below the list for the highlighted tag
+	Details []string
 }
 
-// SelectDeployment shows TUI for deployment selection
-func SelectDeployment(clientset kubernetes.Interface, namespace string) (string, error) {
-	ctx := context.Background()
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return "", err
+// description returns the list description of the tag: age, timestamp, digest, size and platforms
+func (t TagInfo) description() string {
+	parts := []string{formatAge(t.CreatedAt)}
+	if !t.CreatedAt.IsZero() {
+		parts = append(parts, t.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if t.Digest != "" {
+		parts = append(parts, imageref.ShortDigest(t.Digest))
 	}
+	if t.Size > 0 {
+		parts = append(parts, formatSize(t.Size))
+	}
+	if len(t.Architectures) > 0 {
+		parts = append(parts, strings.Join(t.Architectures, " "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// image returns the image of the tag in the repository of ref, or the digest for an untagged manifest
+func (t TagInfo) image(ref imageref.Reference) string {
+	if t.Tag == "" {
+		return ref.Name() + "@" + t.Digest
+	}
+	return ref.WithTag(t.Tag)
+}
 
+// SelectWorkload shows TUI for workload selection
+func SelectWorkload(workloads []WorkloadInfo) (WorkloadInfo, error) {
 	items := []list.Item{}
-	for _, dep := range deployments.Items {
+	for _, workload := range workloads {
 		items = append(items, item{
-			title: dep.Name,
-			desc:  fmt.Sprintf("Replicas: %d, Available: %d", *dep.Spec.Replicas, dep.Status.AvailableReplicas),
+			title: workload.String(),
+			desc:  workload.Summary,
 		})
 	}
 
@@ -151,7 +244,7 @@ func SelectDeployment(clientset kubernetes.Interface, namespace string) (string,
 	const listHeight = 14
 
 	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
-	l.Title = "Select Deployment"
+	l.Title = "Select Workload"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 	l.Styles.Title = titleStyle
@@ -163,23 +256,34 @@ func SelectDeployment(clientset kubernetes.Interface, namespace string) (string,
 	p := tea.NewProgram(m)
 	result, err := p.Run()
 	if err != nil {
-		return "", err
+		return WorkloadInfo{}, err
 	}
 
 	if m := result.(listModel); m.choice != "" {
-		return m.choice, nil
+		for _, workload := range workloads {
+			if workload.String() == m.choice {
+				return workload, nil
+			}
+		}
 	}
 
-	return "", fmt.Errorf("no deployment selected")
+	return WorkloadInfo{}, fmt.Errorf("no workload selected")
 }
 
 // SelectContainer shows TUI for container selection
 func SelectContainer(containers []ContainerInfo) (ContainerInfo, error) {
 	items := []list.Item{}
 	for _, container := range containers {
+		desc := fmt.Sprintf("Current image: %s", container.Image)
+		if container.Init {
+			desc = "[init] " + desc
+		}
+		if len(container.Warnings) > 0 {
+			desc += " ⚠"
+		}
 		items = append(items, item{
 			title: container.Name,
-			desc:  fmt.Sprintf("Current image: %s", container.Image),
+			desc:  desc,
 		})
 	}
 
@@ -194,7 +298,14 @@ func SelectContainer(containers []ContainerInfo) (ContainerInfo, error) {
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
 
-	m := listModel{list: l}
+	m := listModel{list: l, details: func(title string) string {
+		for _, container := range containers {
+			if container.Name == title {
+				return podDetails(container)
+			}
+		}
+		return ""
+	}}
 
 	p := tea.NewProgram(m)
 	result, err := p.Run()
@@ -226,9 +337,12 @@ func SelectImageTag(currentImage string, tags []string) (string, error) {
 	}
 
 	// Add available tags
-	imageName := strings.Split(currentImage, ":")[0]
+	ref, err := imageref.Parse(currentImage)
+	if err != nil {
+		return "", err
+	}
 	for _, tag := range tags {
-		fullImage := fmt.Sprintf("%s:%s", imageName, tag)
+		fullImage := ref.WithTag(tag)
 		if fullImage != currentImage {
 			items = append(items, item{
 				title: fullImage,
@@ -265,47 +379,215 @@ func SelectImageTag(currentImage string, tags []string) (string, error) {
 	return "", fmt.Errorf("no image selected")
 }
 
-// SelectImageTagWithTimestamp shows TUI for image tag selection with timestamps
-func SelectImageTagWithTimestamp(currentImage string, tagInfos []TagInfo) (string, error) {
+// tagOrder is an order of the tag picker
+type tagOrder int
+
+const (
+	orderByDate tagOrder = iota
+	orderBySemver
+	orderByName
+	tagOrders
+)
+
+func (o tagOrder) String() string {
+	switch o {
+	case orderBySemver:
+		return "semver"
+	case orderByName:
+		return "name"
+	}
+	return "date"
+}
+
+// tagListModel is the tag picker; "s" cycles the order of the tags
+type tagListModel struct {
+	listModel
+	ref     imageref.Reference
+	current string
+	tags    []TagInfo
+	order   tagOrder
+}
+
+func (m tagListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "s" && m.list.FilterState() != list.Filtering {
+		m.order = (m.order + 1) % tagOrders
+		m.list.Title = m.title()
+		cmd := m.list.SetItems(m.items())
+		m.list.Select(0)
+		return m, cmd
+	}
+
+	model, cmd := m.listModel.Update(msg)
+	m.listModel = model.(listModel)
+	return m, cmd
+}
+
+// title returns the list title with the current order
+func (m tagListModel) title() string {
+	return fmt.Sprintf("Select Image Tag (by %s)", m.order)
+}
+
+// items returns the current image followed by the tags in the current order
+func (m tagListModel) items() []list.Item {
 	items := []list.Item{}
 
 	// Show current image first
-	if currentImage != "" {
+	if m.current != "" {
 		items = append(items, item{
-			title: fmt.Sprintf("%s (current)", currentImage),
+			title: fmt.Sprintf("%s (current)", m.current),
 			desc:  "Currently deployed",
 		})
 	}
 
-	// Add available tags with timestamps
-	imageName := strings.Split(currentImage, ":")[0]
-	for _, tagInfo := range tagInfos {
-		fullImage := fmt.Sprintf("%s:%s", imageName, tagInfo.Tag)
-		if fullImage != currentImage {
-			var desc string
-			// Since we're using interface{} for CreatedAt, we need to handle different types
-			desc = fmt.Sprintf("Tag: %s", tagInfo.Tag)
-			// Note: Timestamp formatting will be handled by the caller
-
+	tags := append([]TagInfo(nil), m.tags...)
+	sortTags(tags, m.order)
+	for _, tagInfo := range tags {
+		fullImage := tagInfo.image(m.ref)
+		if fullImage != m.current {
 			items = append(items, item{
 				title: fullImage,
-				desc:  desc,
+				desc:  tagInfo.description(),
 			})
 		}
 	}
+	return items
+}
+
+// sortTags sorts tags newest first by creation time or version, or by name
+func sortTags(tags []TagInfo, order tagOrder) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		// Untagged manifests have no name to sort by, so they go last unless sorting by date
+		if order != orderByDate && (tags[i].Tag == "") != (tags[j].Tag == "") {
+			return tags[j].Tag == ""
+		}
+		switch order {
+		case orderBySemver:
+			vi, oki := parseVersion(tags[i].Tag)
+			vj, okj := parseVersion(tags[j].Tag)
+			switch {
+			case oki && okj:
+				if c := compareVersions(vi, vj); c != 0 {
+					return c > 0
+				}
+			case oki != okj:
+				// Tags that aren't versions, like "latest", go last
+				return oki
+			}
+			return tags[i].Tag < tags[j].Tag
+		case orderByName:
+			return tags[i].Tag < tags[j].Tag
+		}
+		return tags[i].CreatedAt.After(tags[j].CreatedAt)
+	})
+}
+
+// version is a tag such as "v1.21.3-alpine" split into numbers and suffix
+type version struct {
+	numbers []int
+	suffix  string
+}
+
+// parseVersion parses tags like "1.21", "v1.21.3" or "1.21.3-alpine"
+func parseVersion(tag string) (version, bool) {
+	core, suffix, _ := strings.Cut(strings.TrimPrefix(tag, "v"), "-")
+	var v version
+	for _, field := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return version{}, false
+		}
+		v.numbers = append(v.numbers, n)
+	}
+	v.suffix = suffix
+	return v, true
+}
+
+// compareVersions compares numbers first, missing ones counting as 0; like semver,
+// a version without suffix ranks above the same version with one, and suffixes
+// compare as pre-releases
+func compareVersions(a, b version) int {
+	for i := 0; i < len(a.numbers) || i < len(b.numbers); i++ {
+		var x, y int
+		if i < len(a.numbers) {
+			x = a.numbers[i]
+		}
+		if i < len(b.numbers) {
+			y = b.numbers[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case a.suffix == b.suffix:
+		return 0
+	case a.suffix == "":
+		return 1
+	case b.suffix == "":
+		return -1
+	}
+	return comparePrereleases(a.suffix, b.suffix)
+}
+
+// comparePrereleases compares semver pre-releases such as "rc.2" and "rc.10" field by field:
+// numeric fields numerically and below alphanumeric ones, and a prefix below the longer version
+func comparePrereleases(a, b string) int {
+	x, y := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(x) && i < len(y); i++ {
+		m, errM := strconv.Atoi(x[i])
+		n, errN := strconv.Atoi(y[i])
+		switch {
+		case errM == nil && errN == nil:
+			if m != n {
+				return cmp.Compare(m, n)
+			}
+		case errM == nil:
+			return -1
+		case errN == nil:
+			return 1
+		default:
+			if c := strings.Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(x), len(y))
+}
+
+// SelectImageTagWithTimestamp shows TUI for image tag selection with age, digest, size and
+// platforms of each tag, sortable by date, semver or name
+func SelectImageTagWithTimestamp(currentImage string, tagInfos []TagInfo) (string, error) {
+	ref, err := imageref.Parse(currentImage)
+	if err != nil {
+		return "", err
+	}
 
 	const defaultWidth = 80
 	const listHeight = 14
 
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
-	l.Title = "Select Image Tag"
+	m := tagListModel{ref: ref, current: currentImage, tags: tagInfos}
+
+	l := list.New(m.items(), itemDelegate{}, defaultWidth, listHeight)
+	l.Title = m.title()
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 	l.Styles.Title = titleStyle
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
-
-	m := listModel{list: l}
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort"))}
+	}
+	m.listModel = listModel{list: l, details: func(title string) string {
+		for _, tagInfo := range tagInfos {
+			if tagInfo.image(ref) == title {
+				return strings.Join(tagInfo.Details, "\n")
+			}
+		}
+		return ""
+	}}
 
 	p := tea.NewProgram(m)
 	result, err := p.Run()
@@ -313,7 +595,7 @@ func SelectImageTagWithTimestamp(currentImage string, tagInfos []TagInfo) (strin
 		return "", err
 	}
 
-	if m := result.(listModel); m.choice != "" {
+	if m := result.(tagListModel); m.choice != "" {
 		// Remove "(current)"
 		choice := strings.Replace(m.choice, " (current)", "", 1)
 		return choice, nil
@@ -322,9 +604,129 @@ func SelectImageTagWithTimestamp(currentImage string, tagInfos []TagInfo) (strin
 	return "", fmt.Errorf("no image selected")
 }
 
-// TUI for rollback confirmation
+// RevisionInfo represents a rollout revision of a deployment
+type RevisionInfo struct {
+	Number      int64
+	Images      string
+	ChangeCause string
+	CreatedAt   time.Time
+	Current     bool
+}
+
+// title returns the list title of the revision
+func (r RevisionInfo) title() string {
+	if r.Current {
+		return fmt.Sprintf("revision %d (current)", r.Number)
+	}
+	return fmt.Sprintf("revision %d", r.Number)
+}
+
+// SelectRevision shows TUI for rollout revision selection
+func SelectRevision(revisions []RevisionInfo) (RevisionInfo, error) {
+	items := []list.Item{}
+	for _, revision := range revisions {
+		desc := fmt.Sprintf("%s, %s", revision.Images, formatAge(revision.CreatedAt))
+		if revision.ChangeCause != "" {
+			desc += fmt.Sprintf(", %s", revision.ChangeCause)
+		}
+		items = append(items, item{
+			title: revision.title(),
+			desc:  desc,
+		})
+	}
+
+	const defaultWidth = 80
+	const listHeight = 14
+
+	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
+	l.Title = "Select Revision"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	l.Styles.HelpStyle = helpStyle
+
+	m := listModel{list: l}
+
+	p := tea.NewProgram(m)
+	result, err := p.Run()
+	if err != nil {
+		return RevisionInfo{}, err
+	}
+
+	if m := result.(listModel); m.choice != "" {
+		for _, revision := range revisions {
+			if revision.title() == m.choice {
+				return revision, nil
+			}
+		}
+	}
+
+	return RevisionInfo{}, fmt.Errorf("no revision selected")
+}
+
+// formatSize formats a byte count in decimal units, like registries show image sizes
+func formatSize(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
+
+// formatAge returns a short relative age such as "5m ago" or "3d ago"
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "unknown age"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+var (
+	diffAddStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	diffHunkStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	diffFileStyle   = lipgloss.NewStyle().Bold(true)
+)
+
+// RenderDiff colors a unified diff. Colors are dropped when the output is not a terminal.
+func RenderDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = diffFileStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemoveStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TUI for rollback and update confirmation
 type confirmModel struct {
 	message string
+	body    string
 	result  bool
 	quit    bool
 }
@@ -354,9 +756,15 @@ func (m confirmModel) View() string {
 		return quitTextStyle.Render("Cancelled.")
 	}
 
+	body := ""
+	if m.body != "" {
+		body = "\n" + m.body + "\n"
+	}
+
 	return fmt.Sprintf(
-		"\n%s\n\n%s",
+		"\n%s\n%s\n%s",
 		titleStyle.Render(m.message),
+		body,
 		helpStyle.Render("Press Y to confirm, N to cancel"),
 	) + "\n"
 }
@@ -380,6 +788,26 @@ func ConfirmRollback(message string) bool {
 	return false
 }
 
+// ConfirmUpdate shows the diff of an update and asks for confirmation before it is applied
+func ConfirmUpdate(message, diff string) bool {
+	m := confirmModel{
+		message: message,
+		body:    RenderDiff(diff),
+	}
+
+	p := tea.NewProgram(m)
+	result, err := p.Run()
+	if err != nil {
+		return false
+	}
+
+	if finalModel := result.(confirmModel); !finalModel.quit {
+		return finalModel.result
+	}
+
+	return false
+}
+
 // TUI for custom image input
 type textInputModel struct {
 	textInput textinput.Model
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag     string
+		numbers []int
+		suffix  string
+		ok      bool
+	}{
+		{"1.21", []int{1, 21}, "", true},
+		{"v1.21.3", []int{1, 21, 3}, "", true},
+		{"1.21.3-alpine", []int{1, 21, 3}, "alpine", true},
+		{"v2.0.0-rc.1", []int{2, 0, 0}, "rc.1", true},
+		{"latest", nil, "", false},
+		{"1.x", nil, "", false},
+		{"", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			v, ok := parseVersion(tt.tag)
+			if ok != tt.ok {
+				t.Fatalf("parseVersion(%q) ok = %v, want %v", tt.tag, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if len(v.numbers) != len(tt.numbers) || v.suffix != tt.suffix {
+				t.Errorf("parseVersion(%q) = %v %q, want %v %q", tt.tag, v.numbers, v.suffix, tt.numbers, tt.suffix)
+			}
+			for i := range tt.numbers {
+				if i < len(v.numbers) && v.numbers[i] != tt.numbers[i] {
+					t.Errorf("parseVersion(%q) = %v, want %v", tt.tag, v.numbers, tt.numbers)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10", "1.9", 1},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.1", "1.2", 1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-rc.2", "1.0.0-rc.1", 1},
+		{"1.0.0-rc.10", "1.0.0-rc.9", 1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0-alpha.1", "1.0.0-alpha", 1},
+		{"1.0.0-alpha.beta", "1.0.0-alpha.1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, _ := parseVersion(tt.a)
+			b, _ := parseVersion(tt.b)
+			if got := compareVersions(a, b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(b, a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTags(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tags := []TagInfo{
+		{Tag: "1.9.0", CreatedAt: base.Add(5 * time.Hour)},
+		{Tag: "latest", CreatedAt: base.Add(6 * time.Hour)},
+		{Tag: "v1.10.0-rc.1", CreatedAt: base.Add(3 * time.Hour)},
+		{Tag: "", Digest: "sha256:untagged", CreatedAt: base.Add(7 * time.Hour)},
+		{Tag: "1.10.0", CreatedAt: base.Add(4 * time.Hour)},
+		{Tag: "main", CreatedAt: base.Add(2 * time.Hour)},
+		{Tag: "v1.10.0-rc.2", CreatedAt: base.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		order tagOrder
+		want  string
+	}{
+		{orderByDate, ",latest,1.9.0,1.10.0,v1.10.0-rc.1,main,v1.10.0-rc.2"},
+		// Tags that aren't versions go after versions, sorted by name; untagged manifests go last
+		{orderBySemver, "1.10.0,v1.10.0-rc.2,v1.10.0-rc.1,1.9.0,latest,main,"},
+		{orderByName, "1.10.0,1.9.0,latest,main,v1.10.0-rc.1,v1.10.0-rc.2,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			sorted := append([]TagInfo(nil), tags...)
+			sortTags(sorted, tt.order)
+
+			names := make([]string, len(sorted))
+			for i, tag := range sorted {
+				names[i] = tag.Tag
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("sortTags(%s) = %s, want %s", tt.order, got, tt.want)
+			}
+		})
+	}
+}